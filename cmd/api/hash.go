@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eduardovfaleiro/gatekeeper/pkg/hash"
+)
+
+// setupHashSchemes registers the password hash schemes that need
+// configuration, and the pepper if one is set. Imported hashes of other
+// schemes are rejected.
+func setupHashSchemes() error {
+	if err := setupPepper(); err != nil {
+		return err
+	}
+
+	signerKey := os.Getenv("FIREBASE_SCRYPT_SIGNER_KEY")
+	if signerKey == "" {
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(signerKey)
+	if err != nil {
+		return errors.New("FIREBASE_SCRYPT_SIGNER_KEY must be base64")
+	}
+
+	separator, err := base64.StdEncoding.DecodeString(os.Getenv("FIREBASE_SCRYPT_SALT_SEPARATOR"))
+	if err != nil {
+		return errors.New("FIREBASE_SCRYPT_SALT_SEPARATOR must be base64")
+	}
+
+	hash.Register(hash.NewFirebaseScrypt(key, separator))
+
+	return nil
+}
+
+// setupPepper reads pepper keys as <version>:<base64 key> entries separated by
+// commas or newlines, from PASSWORD_PEPPER_FILE or else PASSWORD_PEPPERS. The
+// highest version is used for new hashes.
+func setupPepper() error {
+	spec := os.Getenv("PASSWORD_PEPPERS")
+
+	if path := os.Getenv("PASSWORD_PEPPER_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("PASSWORD_PEPPER_FILE: %w", err)
+		}
+		spec = string(data)
+	}
+
+	keys := make(map[int][]byte)
+	current := 0
+
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		v, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return errors.New("pepper entries must look like <version>:<base64 key>")
+		}
+
+		version, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || version < 1 {
+			return fmt.Errorf("invalid pepper version %q", v)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return fmt.Errorf("pepper version %d must be base64", version)
+		}
+
+		keys[version] = key
+		current = max(current, version)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return hash.SetPepper(keys, current)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/encrypt"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
+)
+
+const keyRefreshInterval = time.Minute
+
+// setupKeys builds the JWT key set from JWT_SIGNING_ALG. HS256 keeps using
+// JWT_SECRET and has no key service. Asymmetric algorithms use a key ring
+// persisted in Postgres and encrypted with cipher; JWT_PRIVATE_KEY_FILE, if
+// set, seeds the ring when it is empty.
+func setupKeys(ctx context.Context, db *sql.DB, cipher *encrypt.Cipher) (token.KeySet, service.KeyService, error) {
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	if alg == "" {
+		alg = token.AlgHS256
+	}
+
+	if alg == token.AlgHS256 {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, nil, errors.New("JWT_SECRET is required when JWT_SIGNING_ALG is HS256")
+		}
+		return token.NewStaticKeySet(token.NewHMACSigner("", []byte(secret))), nil, nil
+	}
+
+	var initial token.Signer
+	if keyFile := os.Getenv("JWT_PRIVATE_KEY_FILE"); keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+
+		initial, err = token.ParseSignerPEM(data)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if initial.Method().Alg() != alg {
+			return nil, nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE holds a %s key, expected %s", initial.Method().Alg(), alg)
+		}
+	}
+
+	gracePeriod, err := durationEnv("JWT_KEY_GRACE_PERIOD", 24*time.Hour)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Every instance must have reloaded the pending key and every JWKS cache
+	// expired before the key starts signing.
+	minPublishDelay := token.JWKSMaxAge + keyRefreshInterval
+	publishDelay, err := durationEnv("JWT_KEY_PUBLISH_DELAY", minPublishDelay)
+	if err != nil {
+		return nil, nil, err
+	}
+	if publishDelay < minPublishDelay {
+		publishDelay = minPublishDelay
+	}
+
+	ring := token.NewKeyRing()
+	keySvc := service.NewKeyService(repository.NewPostgresSigningKeyRepository(db), ring, cipher, alg, gracePeriod, publishDelay, initial)
+
+	if err := keySvc.Load(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return ring, keySvc, nil
+}
+
+// runKeyRotation reloads the key ring every minute so that keys rotated by
+// other instances are picked up and pending keys are activated, and rotates
+// once JWT_KEY_ROTATION_INTERVAL has passed since the active key was created.
+func runKeyRotation(ctx context.Context, keySvc service.KeyService) {
+	interval, err := durationEnv("JWT_KEY_ROTATION_INTERVAL", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ticker := time.NewTicker(keyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if interval > 0 {
+			err = keySvc.RotateIfDue(ctx, interval)
+		} else {
+			err = keySvc.Load(ctx)
+		}
+
+		if err != nil {
+			log.Printf("ERROR: signing key refresh: %v", err)
+		}
+	}
+}
+
+func durationEnv(name string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+)
+
+func setupLockoutPolicy() (service.LockoutPolicy, error) {
+	var (
+		policy service.LockoutPolicy
+		err    error
+	)
+
+	if policy.PerEmail.MaxFailures, err = intEnv("LOGIN_MAX_FAILURES_PER_EMAIL", 5); err != nil {
+		return policy, err
+	}
+	if policy.PerIP.MaxFailures, err = intEnv("LOGIN_MAX_FAILURES_PER_IP", 50); err != nil {
+		return policy, err
+	}
+
+	window, err := durationEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute)
+	if err != nil {
+		return policy, err
+	}
+	policy.PerEmail.Window = window
+	policy.PerIP.Window = window
+
+	if policy.BaseLockout, err = durationEnv("LOGIN_LOCKOUT_BASE", time.Minute); err != nil {
+		return policy, err
+	}
+	if policy.MaxLockout, err = durationEnv("LOGIN_LOCKOUT_MAX", time.Hour); err != nil {
+		return policy, err
+	}
+
+	if window <= 0 || policy.BaseLockout <= 0 || policy.MaxLockout < policy.BaseLockout {
+		return policy, errors.New("login lockout durations must be positive and LOGIN_LOCKOUT_MAX at least LOGIN_LOCKOUT_BASE")
+	}
+
+	return policy, nil
+}
+
+func intEnv(name string, fallback int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return n, nil
+}
@@ -5,13 +5,17 @@ import (
 	"database/sql"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/eduardovfaleiro/gatekeeper/internal/handler"
 	"github.com/eduardovfaleiro/gatekeeper/internal/interceptor"
 	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
 	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	"github.com/eduardovfaleiro/gatekeeper/internal/validation"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/encrypt"
 	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -50,19 +54,122 @@ func main() {
 	}
 
 	repo := repository.NewPostgresUserRepository(db)
+	refreshTokenRepo := repository.NewRedisRefreshTokenRepository(rdb)
+	revocationRepo := repository.NewRedisTokenRevocationRepository(rdb)
+	sessionRepo := repository.NewPostgresSessionRepository(db)
+	totpRepo := repository.NewPostgresTOTPRepository(db)
+	recoveryCodeRepo := repository.NewPostgresRecoveryCodeRepository(db)
+	passkeyRepo := repository.NewPostgresWebAuthnCredentialRepository(db)
+	passwordHistoryRepo := repository.NewPostgresPasswordHistoryRepository(db)
 
 	emailSvc := service.NewEmailService()
 
-	svc := service.NewAuthService(repo, rdb, emailSvc)
-	authHandler := handler.NewAuthHandler(svc)
+	smsSvc := service.NewSMSService()
+	if url := os.Getenv("SMS_WEBHOOK_URL"); url != "" {
+		smsSvc = service.NewWebhookSMSService(url, os.Getenv("SMS_WEBHOOK_TOKEN"))
+	}
+
+	if err := setupHashSchemes(); err != nil {
+		log.Fatal("Invalid password hash configuration:", err)
+	}
+
+	cipher, err := encrypt.NewCipherFromBase64(os.Getenv("ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatal("Invalid ENCRYPTION_KEY:", err)
+	}
+
+	keys, keySvc, err := setupKeys(ctx, db, cipher)
+	if err != nil {
+		log.Fatal("Could not load JWT signing keys:", err)
+	}
+
+	if keySvc != nil {
+		go runKeyRotation(ctx, keySvc)
+	}
+
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "Gatekeeper"
+	}
+
+	wa, err := setupWebAuthn()
+	if err != nil {
+		log.Fatal("Invalid WebAuthn configuration:", err)
+	}
+
+	verificationPolicy := service.EmailVerificationPolicy(os.Getenv("EMAIL_VERIFICATION_POLICY"))
+	switch verificationPolicy {
+	case "":
+		verificationPolicy = service.EmailVerificationClaim
+	case service.EmailVerificationClaim, service.EmailVerificationRequired:
+	default:
+		log.Fatalf("Invalid EMAIL_VERIFICATION_POLICY: %q", verificationPolicy)
+	}
+
+	lockoutPolicy, err := setupLockoutPolicy()
+	if err != nil {
+		log.Fatal("Invalid login lockout configuration:", err)
+	}
+
+	var revokeOnEmailChange bool
+	if v := os.Getenv("EMAIL_CHANGE_REVOKE_SESSIONS"); v != "" {
+		if revokeOnEmailChange, err = strconv.ParseBool(v); err != nil {
+			log.Fatalf("Invalid EMAIL_CHANGE_REVOKE_SESSIONS: %q", v)
+		}
+	}
+
+	var emailNormalizer validation.EmailNormalizer
+	if v := os.Getenv("EMAIL_LOWERCASE_LOCAL_PART"); v != "" {
+		if emailNormalizer.LowercaseLocalPart, err = strconv.ParseBool(v); err != nil {
+			log.Fatalf("Invalid EMAIL_LOWERCASE_LOCAL_PART: %q", v)
+		}
+	}
+
+	passwordPolicy, err := setupPasswordPolicy()
+	if err != nil {
+		log.Fatal("Invalid password policy:", err)
+	}
+
+	rateLimits, err := setupRateLimits()
+	if err != nil {
+		log.Fatal("Invalid RATE_LIMITS:", err)
+	}
+
+	sessionSvc := service.NewSessionService(sessionRepo, refreshTokenRepo, revocationRepo)
+	mfaSvc := service.NewMFAService(totpRepo, recoveryCodeRepo, passkeyRepo, repo, rdb, cipher, totpIssuer)
+	passkeySvc := service.NewPasskeyService(passkeyRepo, repo, rdb, wa)
+	lockoutSvc := service.NewLockoutService(repo, rdb, lockoutPolicy)
+	svc := service.NewAuthService(repo, refreshTokenRepo, revocationRepo, sessionSvc, mfaSvc, passkeySvc, lockoutSvc, keys, rdb, emailSvc, smsSvc, verificationPolicy, passwordPolicy, passwordHistoryRepo, emailNormalizer, revokeOnEmailChange)
+	authHandler := handler.NewAuthHandler(svc, sessionSvc, mfaSvc, passkeySvc)
+	adminHandler := handler.NewAdminHandler(keySvc, lockoutSvc, service.NewUserImportService(repo, emailNormalizer))
 
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(interceptor.AuthInterceptor(os.Getenv("JWT_SECRET"))),
+		grpc.ChainUnaryInterceptor(
+			interceptor.AdminInterceptor(os.Getenv("ADMIN_API_KEY")),
+			interceptor.AuthInterceptor(keys, revocationRepo, repo),
+			interceptor.RateLimitInterceptor(rdb, rateLimits),
+		),
 	)
 
 	authpb.RegisterAuthServiceServer(grpcServer, authHandler)
+	authpb.RegisterAdminServiceServer(grpcServer, adminHandler)
 	reflection.Register(grpcServer)
 
+	httpAddr := os.Getenv("HTTP_ADDR")
+	if httpAddr == "" {
+		httpAddr = ":8080"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/.well-known/jwks.json", handler.NewJWKSHandler(svc))
+
+	go func() {
+		log.Printf("HTTP server running on %s", httpAddr)
+		if err := http.ListenAndServe(httpAddr, mux); err != nil {
+			log.Fatalf("failed to serve http: %v", err)
+		}
+	}()
+
 	lis, err := net.Listen("tcp", ":50051")
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/validation"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/hibp"
+)
+
+// setupPasswordPolicy starts from validation.DefaultPasswordPolicy and applies
+// the PASSWORD_* settings that are set. With HIBP_INDEX_FILE it also rejects
+// passwords found in that breach index, which stays mapped for the life of
+// the process.
+func setupPasswordPolicy() (validation.PasswordPolicy, error) {
+	policy := validation.DefaultPasswordPolicy
+
+	var err error
+	if policy.MinLength, err = intEnv("PASSWORD_MIN_LENGTH", policy.MinLength); err != nil {
+		return policy, err
+	}
+	if policy.MaxLength, err = intEnv("PASSWORD_MAX_LENGTH", policy.MaxLength); err != nil {
+		return policy, err
+	}
+	if policy.MinCharacterClasses, err = intEnv("PASSWORD_MIN_CHARACTER_CLASSES", policy.MinCharacterClasses); err != nil {
+		return policy, err
+	}
+	if policy.MaxRepeat, err = intEnv("PASSWORD_MAX_REPEAT", policy.MaxRepeat); err != nil {
+		return policy, err
+	}
+	if policy.History, err = intEnv("PASSWORD_HISTORY", policy.History); err != nil {
+		return policy, err
+	}
+
+	if v := os.Getenv("PASSWORD_MIN_ENTROPY_BITS"); v != "" {
+		if policy.MinEntropyBits, err = strconv.ParseFloat(v, 64); err != nil {
+			return policy, fmt.Errorf("PASSWORD_MIN_ENTROPY_BITS: %w", err)
+		}
+	}
+
+	if v := os.Getenv("PASSWORD_DENY_EMAIL_PARTS"); v != "" {
+		if policy.DenyEmailParts, err = strconv.ParseBool(v); err != nil {
+			return policy, fmt.Errorf("PASSWORD_DENY_EMAIL_PARTS: %w", err)
+		}
+	}
+
+	if policy.MaxLength > 0 && policy.MaxLength < policy.MinLength {
+		return policy, fmt.Errorf("PASSWORD_MAX_LENGTH is below PASSWORD_MIN_LENGTH")
+	}
+
+	if path := os.Getenv("HIBP_INDEX_FILE"); path != "" {
+		index, err := hibp.Open(path)
+		if err != nil {
+			return policy, fmt.Errorf("HIBP_INDEX_FILE: %w", err)
+		}
+		policy.Breached = index
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"maps"
+	"os"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/interceptor"
+)
+
+// defaultRateLimits protects the unauthenticated methods that create accounts
+// or send email or SMS, and bounds password and TOTP guesses made with a
+// stolen access token.
+const defaultRateLimits = "" +
+	"/auth.AuthService/Register=5/1h@ip," +
+	"/auth.AuthService/ForgotPassword=3/1h@email," +
+	"/auth.AuthService/ForgotPassword=20/1h@ip," +
+	"/auth.AuthService/ResendVerificationEmail=3/1h@email," +
+	"/auth.AuthService/ResendVerificationEmail=20/1h@ip," +
+	"/auth.AuthService/RequestMagicLink=5/1h@email," +
+	"/auth.AuthService/RequestMagicLink=20/1h@ip," +
+	"/auth.AuthService/RequestLoginCode=5/1h@email," +
+	"/auth.AuthService/RequestLoginCode=20/1h@ip," +
+	"/auth.AuthService/RequestMFAEmailCode=5/15m@ip," +
+	"/auth.AuthService/ChangePassword=5/15m@user," +
+	"/auth.AuthService/ConfirmTOTP=5/15m@user," +
+	"/auth.AuthService/DisableTOTP=5/15m@user," +
+	"/auth.AuthService/RegenerateRecoveryCodes=5/15m@user," +
+	"/auth.AuthService/RequestEmailChange=5/1h@user," +
+	"/auth.AuthService/RequestPhoneVerification=3/1h@user," +
+	"/auth.AuthService/UpdatePhoneNumber=5/15m@user," +
+	"/auth.AuthService/RequestMFASMSCode=3/15m@mfa_token," +
+	"/auth.AuthService/RequestMFASMSCode=5/15m@ip"
+
+// setupRateLimits starts from the defaults; methods listed in RATE_LIMITS
+// replace their default limits.
+func setupRateLimits() (map[string][]interceptor.RateLimit, error) {
+	limits, err := interceptor.ParseRateLimits(defaultRateLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := interceptor.ParseRateLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		return nil, err
+	}
+
+	maps.Copy(limits, overrides)
+
+	return limits, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// setupWebAuthn configures the relying party used for passkeys. The origins
+// default to https://<WEBAUTHN_RP_ID>.
+func setupWebAuthn() (*webauthn.WebAuthn, error) {
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		rpID = "localhost"
+	}
+
+	displayName := os.Getenv("WEBAUTHN_RP_DISPLAY_NAME")
+	if displayName == "" {
+		displayName = "Gatekeeper"
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_RP_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{"https://" + rpID}
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: displayName,
+		RPOrigins:     origins,
+	})
+}
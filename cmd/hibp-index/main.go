@@ -0,0 +1,40 @@
+// Command hibp-index builds the breached password index read from
+// HIBP_INDEX_FILE out of the Have I Been Pwned SHA-1 text file.
+//
+//	hibp-index -in pwned-passwords-sha1-ordered-by-hash-v8.txt -out hibp.idx
+//
+// The input may also be piped on stdin, for example straight from 7z.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eduardovfaleiro/gatekeeper/pkg/hibp"
+)
+
+func main() {
+	in := flag.String("in", "-", "HIBP SHA-1 text file sorted by hash, or - for stdin")
+	out := flag.String("out", "hibp.idx", "index file to write")
+	recordSize := flag.Int("bytes", hibp.DefaultRecordSize, "leading bytes of each SHA-1 to keep")
+	flag.Parse()
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	count, err := hibp.Build(r, *out, *recordSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Wrote %d hashes to %s", count, *out)
+}
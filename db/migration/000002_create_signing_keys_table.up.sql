@@ -0,0 +1,12 @@
+create table "signing_keys" (
+	kid text primary key,
+	algorithm varchar(10) not null,
+	private_key bytea not null,
+	state varchar(10) not null,
+	created_at TIMESTAMP WITH TIME ZONE not null,
+	retiring_at TIMESTAMP WITH TIME ZONE,
+	retired_at TIMESTAMP WITH TIME ZONE
+);
+
+create unique index signing_keys_single_active on "signing_keys" (state) where state = 'active';
+create unique index signing_keys_single_pending on "signing_keys" (state) where state = 'pending';
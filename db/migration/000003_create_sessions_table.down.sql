@@ -0,0 +1 @@
+drop table if exists "sessions";
\ No newline at end of file
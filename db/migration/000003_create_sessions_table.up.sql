@@ -0,0 +1,12 @@
+create table "sessions" (
+	id uuid primary key,
+	user_id uuid not null references users(id) on delete cascade,
+	device varchar(100) not null default '',
+	user_agent text not null default '',
+	ip_address varchar(45) not null default '',
+	created_at TIMESTAMP WITH TIME ZONE not null,
+	last_seen_at TIMESTAMP WITH TIME ZONE not null,
+	revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+create index sessions_user_id_idx on "sessions" (user_id) where revoked_at is null;
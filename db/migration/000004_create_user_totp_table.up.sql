@@ -0,0 +1,6 @@
+create table "user_totp" (
+	user_id uuid primary key references users(id) on delete cascade,
+	secret bytea not null,
+	confirmed_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE not null
+);
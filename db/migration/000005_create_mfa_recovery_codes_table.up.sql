@@ -0,0 +1,10 @@
+create table "mfa_recovery_codes" (
+	id uuid primary key,
+	user_id uuid not null references users(id) on delete cascade,
+	code_prefix varchar(10) not null,
+	code_hash text not null,
+	used_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE not null
+);
+
+create index mfa_recovery_codes_user_id_idx on "mfa_recovery_codes" (user_id, code_prefix);
@@ -0,0 +1 @@
+drop table if exists "webauthn_credentials";
\ No newline at end of file
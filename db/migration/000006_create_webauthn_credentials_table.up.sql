@@ -0,0 +1,16 @@
+create table "webauthn_credentials" (
+	id bytea primary key,
+	user_id uuid not null references users(id) on delete cascade,
+	name varchar(100) not null default '',
+	public_key bytea not null,
+	attestation_type varchar(32) not null default '',
+	transports text[] not null default '{}',
+	aaguid bytea,
+	sign_count bigint not null default 0,
+	backup_eligible boolean not null default false,
+	backup_state boolean not null default false,
+	created_at TIMESTAMP WITH TIME ZONE not null,
+	last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+create index webauthn_credentials_user_id_idx on "webauthn_credentials" (user_id);
@@ -0,0 +1,4 @@
+alter table "users" add column email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Accounts created before verification existed keep working as before.
+update "users" set email_verified_at = created_at;
@@ -0,0 +1,8 @@
+create table "password_history" (
+	id uuid primary key,
+	user_id uuid not null references users(id) on delete cascade,
+	password_hash text not null,
+	created_at TIMESTAMP WITH TIME ZONE not null
+);
+
+create index password_history_user_id_created_at_idx on "password_history" (user_id, created_at desc);
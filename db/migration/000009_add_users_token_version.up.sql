@@ -0,0 +1 @@
+alter table "users" add column token_version integer not null default 0;
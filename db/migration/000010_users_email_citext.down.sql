@@ -0,0 +1,9 @@
+do $$
+begin
+	if exists (select 1 from "users" where length(email) > 100) then
+		raise exception 'users.email holds addresses longer than 100 characters';
+	end if;
+end $$;
+
+alter table "users" drop constraint users_email_length;
+alter table "users" alter column email type varchar(100);
@@ -0,0 +1,19 @@
+-- Accounts whose addresses differ only in case or surrounding spaces make the
+-- unique index fail below. List them with cmd/email-duplicates and merge or
+-- rename them before running this migration. Internationalized domains have
+-- to be converted to punycode first, which cmd/email-duplicates -fix does.
+do $$
+begin
+	if exists (select 1 from "users" where substring(email from '@([^@]*)$') !~ '^[\x01-\x7f]*$') then
+		raise exception 'users.email holds internationalized domains, run cmd/email-duplicates -fix first';
+	end if;
+end $$;
+
+create extension if not exists citext;
+
+update "users"
+set email = substring(trim(email) from '^(.*)@') || '@' || regexp_replace(lower(substring(trim(email) from '@([^@]*)$')), '\.$', '')
+where email like '%@%';
+
+alter table "users" alter column email type citext;
+alter table "users" add constraint users_email_length check (length(email) <= 254);
@@ -0,0 +1,2 @@
+alter table "users" drop column phone_number;
+alter table "users" drop column username;
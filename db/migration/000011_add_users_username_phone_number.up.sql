@@ -0,0 +1,2 @@
+alter table "users" add column username citext unique;
+alter table "users" add column phone_number varchar(16) unique;
@@ -0,0 +1 @@
+alter table "users" add column phone_verified_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,52 @@
+package clientinfo
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type Info struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
+
+// FromContext reads the caller's IP from the gRPC peer and its user agent
+// from the request metadata. Device is left for the caller to fill in.
+func FromContext(ctx context.Context) Info {
+	return Info{
+		UserAgent: UserAgent(ctx),
+		IP:        IP(ctx),
+	}
+}
+
+func IP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+func UserAgent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	ua := md.Get("user-agent")
+	if len(ua) == 0 {
+		return ""
+	}
+
+	return ua[0]
+}
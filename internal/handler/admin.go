@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type AdminHandler struct {
+	authpb.UnimplementedAdminServiceServer
+	keys     service.KeyService
+	lockout  service.LockoutService
+	importer service.UserImportService
+}
+
+// NewAdminHandler builds the admin API. keys is nil when tokens are signed
+// with a static HS256 secret, in which case key rotation is unavailable.
+func NewAdminHandler(keys service.KeyService, lockout service.LockoutService, importer service.UserImportService) *AdminHandler {
+	return &AdminHandler{keys: keys, lockout: lockout, importer: importer}
+}
+
+func (h *AdminHandler) RotateSigningKeys(ctx context.Context, req *authpb.RotateSigningKeysRequest) (*authpb.RotateSigningKeysResponse, error) {
+	if h.keys == nil {
+		return nil, status.Error(codes.FailedPrecondition, "key rotation requires an asymmetric signing algorithm")
+	}
+
+	rotation, err := h.keys.Rotate(ctx)
+	if err != nil {
+		if errors.Is(err, service.ErrKeyRotationPending) {
+			return nil, status.Error(codes.FailedPrecondition, "a key rotation is already pending")
+		}
+		log.Printf("ERROR: AdminHandler.RotateSigningKeys failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RotateSigningKeysResponse{
+		ActiveKeyId:  rotation.ActiveKeyID,
+		PendingKeyId: rotation.PendingKeyID,
+		ActivatesAt:  rotation.ActivatesAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (h *AdminHandler) UnlockUser(ctx context.Context, req *authpb.UnlockUserRequest) (*authpb.UnlockUserResponse, error) {
+	userID, err := model.ParseID(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user id")
+	}
+
+	err = h.lockout.UnlockUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		log.Printf("ERROR: AdminHandler.UnlockUser failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.UnlockUserResponse{
+		Message: "User unlocked successfully",
+	}, nil
+}
+
+func (h *AdminHandler) ImportUsers(ctx context.Context, req *authpb.ImportUsersRequest) (*authpb.ImportUsersResponse, error) {
+	result, err := h.importer.Import(ctx, bytes.NewReader(req.Data), req.Format)
+	if err != nil {
+		if errors.Is(err, service.ErrUnsupportedImportFormat) {
+			return nil, status.Error(codes.InvalidArgument, "format must be csv or jsonl")
+		}
+		var fileErr *service.ImportFileError
+		if errors.As(err, &fileErr) {
+			return nil, status.Error(codes.InvalidArgument, fileErr.Error())
+		}
+
+		log.Printf("ERROR: AdminHandler.ImportUsers failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &authpb.ImportUsersResponse{
+		Imported: int32(result.Imported),
+		Skipped:  int32(result.Skipped),
+	}
+
+	for _, e := range result.Errors {
+		resp.Errors = append(resp.Errors, &authpb.ImportUserError{
+			Line:    int32(e.Line),
+			Email:   e.Email,
+			Message: e.Message,
+		})
+	}
+
+	return resp, nil
+}
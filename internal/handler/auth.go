@@ -7,21 +7,30 @@ import (
 	"log"
 	"time"
 
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/interceptor"
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
 	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
 	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	"github.com/eduardovfaleiro/gatekeeper/internal/validation"
 	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
 	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 type AuthHandler struct {
 	authpb.UnimplementedAuthServiceServer
-	svc service.AuthService
+	svc      service.AuthService
+	sessions service.SessionService
+	mfa      service.MFAService
+	passkeys service.PasskeyService
 }
 
-func NewAuthHandler(svc service.AuthService) *AuthHandler {
-	return &AuthHandler{svc: svc}
+func NewAuthHandler(svc service.AuthService, sessions service.SessionService, mfa service.MFAService, passkeys service.PasskeyService) *AuthHandler {
+	return &AuthHandler{svc: svc, sessions: sessions, mfa: mfa, passkeys: passkeys}
 }
 
 var validate = validator.New()
@@ -30,35 +39,128 @@ func (h *AuthHandler) Register(ctx context.Context, req *authpb.RegisterRequest)
 	if err := validate.Var(req.Email, "required,email"); err != nil {
 		return nil, fmt.Errorf("invalid email format")
 	}
-	if err := h.validatePassword(req.Password); err != nil {
-		return nil, fmt.Errorf("password length must be between 8 and 32 characteres")
-	}
 
-	user, err := h.svc.Register(ctx, req.Email, req.Password)
+	identifiers := service.Identifiers{Username: req.Username, PhoneNumber: req.PhoneNumber}
+
+	user, err := h.svc.Register(ctx, req.Email, req.Password, identifiers)
 	if err != nil {
 		if errors.Is(err, repository.ErrUniqueConstraint) {
+			if identifiers != (service.Identifiers{}) {
+				return nil, status.Error(codes.AlreadyExists, "email, username or phone number already in use")
+			}
 			return nil, status.Error(codes.AlreadyExists, "email already in use")
 		}
+		if errors.Is(err, validation.ErrInvalidEmail) {
+			return nil, status.Error(codes.InvalidArgument, "invalid email format")
+		}
+		if errors.Is(err, validation.ErrInvalidUsername) {
+			return nil, status.Error(codes.InvalidArgument, "username must be 3 to 30 letters, digits, dots or underscores")
+		}
+		if errors.Is(err, validation.ErrInvalidPhoneNumber) {
+			return nil, status.Error(codes.InvalidArgument, "phone number must be in E.164 format")
+		}
+		if st := passwordPolicyStatusError(err, "password"); st != nil {
+			return nil, st
+		}
 
 		return nil, err
 	}
 
-	return &authpb.RegisterResponse{
+	resp := &authpb.RegisterResponse{
 		Id:        user.ID.String(),
 		Email:     user.Email,
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
-	}, nil
+	}
+	if user.Username != nil {
+		resp.Username = *user.Username
+	}
+	if user.PhoneNumber != nil {
+		resp.PhoneNumber = *user.PhoneNumber
+	}
+
+	return resp, nil
 }
 
 func (h *AuthHandler) Login(ctx context.Context, req *authpb.LoginRequest) (*authpb.LoginResponse, error) {
-	token, err := h.svc.Login(ctx, req.Email, req.Password)
+	client := clientinfo.FromContext(ctx)
+	client.Device = req.DeviceName
+
+	identifier := req.Identifier
+	if identifier == "" {
+		identifier = req.Email
+	}
+
+	result, err := h.svc.Login(ctx, identifier, req.Password, client)
+
+	if err != nil {
+		var lockout *service.LockoutError
+		if errors.As(err, &lockout) {
+			return nil, lockoutStatusError(lockout)
+		}
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			return nil, status.Error(codes.FailedPrecondition, "email not verified")
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		}
+		log.Printf("ERROR: AuthHandler.Login failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
 
+	return loginResponse(result), nil
+}
+
+// lockoutStatusError tells the client when to try again through a RetryInfo
+// detail.
+func lockoutStatusError(lockout *service.LockoutError) error {
+	st := status.New(codes.ResourceExhausted, "too many failed login attempts")
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(lockout.RetryAfter.Round(time.Second)),
+	})
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+func loginResponse(result *service.LoginResult) *authpb.LoginResponse {
+	if result.Tokens == nil {
+		return &authpb.LoginResponse{
+			MfaRequired: true,
+			MfaToken:    result.MFAToken,
+		}
 	}
 
 	return &authpb.LoginResponse{
-		AccessToken: token,
+		AccessToken:  result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
+	}
+}
+
+func (h *AuthHandler) RefreshToken(ctx context.Context, req *authpb.RefreshTokenRequest) (*authpb.RefreshTokenResponse, error) {
+	if err := validate.Var(req.RefreshToken, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	tokens, err := h.svc.RefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			log.Printf("WARN: AuthHandler.RefreshToken: reused refresh token, token family revoked")
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		case errors.Is(err, service.ErrInvalidRefreshToken):
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
+
+		log.Printf("ERROR: AuthHandler.RefreshToken failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RefreshTokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
@@ -83,15 +185,15 @@ func (h *AuthHandler) ResetPassword(ctx context.Context, req *authpb.ResetPasswo
 	if err := validate.Var(req.Token, "required"); err != nil {
 		return nil, status.Error(codes.InvalidArgument, "token is required")
 	}
-	if err := h.validatePassword(req.NewPassword); err != nil {
-		return nil, status.Error(codes.InvalidArgument, "password length must be between 8 and 32 characters")
-	}
 
 	err := h.svc.ResetPassword(ctx, req.Token, req.NewPassword)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return nil, status.Error(codes.NotFound, "invalid or expired token")
 		}
+		if st := passwordPolicyStatusError(err, "new_password"); st != nil {
+			return nil, st
+		}
 
 		log.Printf("ERROR: AuthHandler.ResetPassword failure: %v", err)
 		return nil, status.Error(codes.Internal, "internal server error")
@@ -102,10 +204,207 @@ func (h *AuthHandler) ResetPassword(ctx context.Context, req *authpb.ResetPasswo
 	}, nil
 }
 
-func (h *AuthHandler) validatePassword(password string) error {
-	err := validate.Var(password, "required,min=8,max=32")
+func (h *AuthHandler) ChangePassword(ctx context.Context, req *authpb.ChangePasswordRequest) (*authpb.ChangePasswordResponse, error) {
+	claims, ok := interceptor.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := validate.Var(req.CurrentPassword, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "current_password is required")
+	}
+
+	err := h.svc.ChangePassword(ctx, claims, req.CurrentPassword, req.NewPassword, req.RevokeOtherSessions, clientinfo.FromContext(ctx))
+	if err != nil {
+		var lockout *service.LockoutError
+		if errors.As(err, &lockout) {
+			return nil, lockoutStatusError(lockout)
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return nil, status.Error(codes.PermissionDenied, "current password is incorrect")
+		}
+		if st := passwordPolicyStatusError(err, "new_password"); st != nil {
+			return nil, st
+		}
+
+		log.Printf("ERROR: AuthHandler.ChangePassword failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.ChangePasswordResponse{
+		Message: "Password changed successfully",
+	}, nil
+}
+
+func (h *AuthHandler) VerifyEmail(ctx context.Context, req *authpb.VerifyEmailRequest) (*authpb.VerifyEmailResponse, error) {
+	if err := validate.Var(req.Token, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	err := h.svc.VerifyEmail(ctx, req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidVerificationToken) {
+			return nil, status.Error(codes.NotFound, "invalid or expired token")
+		}
+
+		log.Printf("ERROR: AuthHandler.VerifyEmail failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.VerifyEmailResponse{
+		Message: "Email verified successfully",
+	}, nil
+}
+
+func (h *AuthHandler) ResendVerificationEmail(ctx context.Context, req *authpb.ResendVerificationEmailRequest) (*authpb.ResendVerificationEmailResponse, error) {
+	if err := validate.Var(req.Email, "required,email"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email format")
+	}
+
+	if err := h.svc.ResendVerificationEmail(ctx, req.Email); err != nil {
+		log.Printf("ERROR: AuthHandler.ResendVerificationEmail failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.ResendVerificationEmailResponse{
+		Message: "If the email exists and is not verified yet, a verification link was sent.",
+	}, nil
+}
+
+func (h *AuthHandler) Logout(ctx context.Context, req *authpb.LogoutRequest) (*authpb.LogoutResponse, error) {
+	claims, ok := interceptor.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	err := h.svc.Logout(ctx, claims)
+	if err != nil {
+		log.Printf("ERROR: AuthHandler.Logout failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.LogoutResponse{
+		Message: "Logged out successfully",
+	}, nil
+}
+
+func (h *AuthHandler) LogoutAllSessions(ctx context.Context, req *authpb.LogoutAllSessionsRequest) (*authpb.LogoutAllSessionsResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := h.svc.LogoutAllSessions(ctx, userID); err != nil {
+		log.Printf("ERROR: AuthHandler.LogoutAllSessions failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.LogoutAllSessionsResponse{
+		Message: "All sessions logged out successfully",
+	}, nil
+}
+
+func (h *AuthHandler) ListSessions(ctx context.Context, req *authpb.ListSessionsRequest) (*authpb.ListSessionsResponse, error) {
+	claims, ok := interceptor.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	sessions, err := h.sessions.List(ctx, claims.UserID)
 	if err != nil {
-		return status.Error(codes.InvalidArgument, "password length must be between 8 and 32 characters")
+		log.Printf("ERROR: AuthHandler.ListSessions failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
 	}
-	return nil
+
+	resp := &authpb.ListSessionsResponse{
+		Sessions: make([]*authpb.Session, 0, len(sessions)),
+	}
+
+	for _, s := range sessions {
+		resp.Sessions = append(resp.Sessions, &authpb.Session{
+			Id:         s.ID.String(),
+			Device:     s.Device,
+			UserAgent:  s.UserAgent,
+			IpAddress:  s.IPAddress,
+			CreatedAt:  s.CreatedAt.Format(time.RFC3339),
+			LastSeenAt: s.LastSeenAt.Format(time.RFC3339),
+			Current:    s.ID == claims.SessionID,
+		})
+	}
+
+	return resp, nil
+}
+
+func (h *AuthHandler) RevokeSession(ctx context.Context, req *authpb.RevokeSessionRequest) (*authpb.RevokeSessionResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	sessionID, err := model.ParseID(req.SessionId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid session id")
+	}
+
+	err = h.sessions.Revoke(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "session not found")
+		}
+
+		log.Printf("ERROR: AuthHandler.RevokeSession failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RevokeSessionResponse{
+		Message: "Session revoked successfully",
+	}, nil
+}
+
+func (h *AuthHandler) GetJWKS(ctx context.Context, req *authpb.GetJWKSRequest) (*authpb.GetJWKSResponse, error) {
+	set := h.svc.JWKS()
+
+	keys := make([]*authpb.JWK, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		keys = append(keys, &authpb.JWK{
+			Kty: k.Kty,
+			Kid: k.Kid,
+			Use: k.Use,
+			Alg: k.Alg,
+			N:   k.N,
+			E:   k.E,
+			Crv: k.Crv,
+			X:   k.X,
+			Y:   k.Y,
+		})
+	}
+
+	return &authpb.GetJWKSResponse{Keys: keys}, nil
+}
+
+// passwordPolicyStatusError reports each broken password rule as a BadRequest
+// field violation on field. It returns nil for other errors.
+func passwordPolicyStatusError(err error, field string) error {
+	var policyErr *validation.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range policyErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: v.Description,
+			Reason:      v.Reason,
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, "password does not meet the password policy")
+
+	detailed, detailErr := st.WithDetails(badRequest)
+	if detailErr != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
 }
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/interceptor"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	"github.com/eduardovfaleiro/gatekeeper/internal/validation"
+	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (h *AuthHandler) RequestEmailChange(ctx context.Context, req *authpb.RequestEmailChangeRequest) (*authpb.RequestEmailChangeResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := validate.Var(req.NewEmail, "required,email"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email format")
+	}
+
+	if err := validate.Var(req.CurrentPassword, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "current_password is required")
+	}
+
+	err := h.svc.RequestEmailChange(ctx, userID, req.NewEmail, req.CurrentPassword, clientinfo.FromContext(ctx))
+	if err != nil {
+		var lockout *service.LockoutError
+		if errors.As(err, &lockout) {
+			return nil, lockoutStatusError(lockout)
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return nil, status.Error(codes.PermissionDenied, "current password is incorrect")
+		}
+		if errors.Is(err, validation.ErrInvalidEmail) {
+			return nil, status.Error(codes.InvalidArgument, "invalid email format")
+		}
+		if errors.Is(err, service.ErrEmailUnchanged) {
+			return nil, status.Error(codes.InvalidArgument, "new email matches the current one")
+		}
+
+		log.Printf("ERROR: AuthHandler.RequestEmailChange failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RequestEmailChangeResponse{
+		Message: "Confirmation sent to the new email address",
+	}, nil
+}
+
+func (h *AuthHandler) ConfirmEmailChange(ctx context.Context, req *authpb.ConfirmEmailChangeRequest) (*authpb.ConfirmEmailChangeResponse, error) {
+	if err := validate.Var(req.Token, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	err := h.svc.ConfirmEmailChange(ctx, req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidEmailChangeToken) {
+			return nil, status.Error(codes.NotFound, "invalid or expired token")
+		}
+		if errors.Is(err, repository.ErrUniqueConstraint) {
+			return nil, status.Error(codes.AlreadyExists, "email already in use")
+		}
+
+		log.Printf("ERROR: AuthHandler.ConfirmEmailChange failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.ConfirmEmailChangeResponse{
+		Message: "Email changed successfully",
+	}, nil
+}
+
+func (h *AuthHandler) UndoEmailChange(ctx context.Context, req *authpb.UndoEmailChangeRequest) (*authpb.UndoEmailChangeResponse, error) {
+	if err := validate.Var(req.Token, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	err := h.svc.UndoEmailChange(ctx, req.Token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidEmailChangeToken) {
+			return nil, status.Error(codes.NotFound, "invalid or expired token")
+		}
+		if errors.Is(err, repository.ErrUniqueConstraint) {
+			return nil, status.Error(codes.AlreadyExists, "email already in use")
+		}
+
+		log.Printf("ERROR: AuthHandler.UndoEmailChange failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.UndoEmailChangeResponse{
+		Message: "Email change undone, all sessions were signed out",
+	}, nil
+}
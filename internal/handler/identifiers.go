@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/interceptor"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	"github.com/eduardovfaleiro/gatekeeper/internal/validation"
+	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (h *AuthHandler) UpdateUsername(ctx context.Context, req *authpb.UpdateUsernameRequest) (*authpb.UpdateUsernameResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	user, err := h.svc.UpdateUsername(ctx, userID, req.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, validation.ErrInvalidUsername):
+			return nil, status.Error(codes.InvalidArgument, "username must be 3 to 30 letters, digits, dots or underscores")
+		case errors.Is(err, repository.ErrUniqueConstraint):
+			return nil, status.Error(codes.AlreadyExists, "username already in use")
+		}
+
+		log.Printf("ERROR: AuthHandler.UpdateUsername failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &authpb.UpdateUsernameResponse{}
+	if user.Username != nil {
+		resp.Username = *user.Username
+	}
+
+	return resp, nil
+}
+
+func (h *AuthHandler) UpdatePhoneNumber(ctx context.Context, req *authpb.UpdatePhoneNumberRequest) (*authpb.UpdatePhoneNumberResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := validate.Var(req.CurrentPassword, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "current_password is required")
+	}
+
+	user, err := h.svc.UpdatePhoneNumber(ctx, userID, req.PhoneNumber, req.CurrentPassword, clientinfo.FromContext(ctx))
+	if err != nil {
+		var lockout *service.LockoutError
+		switch {
+		case errors.As(err, &lockout):
+			return nil, lockoutStatusError(lockout)
+		case errors.Is(err, service.ErrInvalidCredentials):
+			return nil, status.Error(codes.PermissionDenied, "current password is incorrect")
+		case errors.Is(err, validation.ErrInvalidPhoneNumber):
+			return nil, status.Error(codes.InvalidArgument, "phone number must be in E.164 format")
+		case errors.Is(err, repository.ErrUniqueConstraint):
+			return nil, status.Error(codes.AlreadyExists, "phone number already in use")
+		}
+
+		log.Printf("ERROR: AuthHandler.UpdatePhoneNumber failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &authpb.UpdatePhoneNumberResponse{}
+	if user.PhoneNumber != nil {
+		resp.PhoneNumber = *user.PhoneNumber
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
+)
+
+// NewJWKSHandler serves the public signing keys at /.well-known/jwks.json so
+// that other services can verify tokens without holding any secret.
+func NewJWKSHandler(svc service.AuthService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(token.JWKSMaxAge.Seconds())))
+
+		if err := json.NewEncoder(w).Encode(svc.JWKS()); err != nil {
+			log.Printf("ERROR: JWKSHandler encode: %v", err)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/interceptor"
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (h *AuthHandler) VerifyMFA(ctx context.Context, req *authpb.VerifyMFARequest) (*authpb.LoginResponse, error) {
+	if err := validate.Var(req.MfaToken, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "mfa token is required")
+	}
+	if req.Code == "" && req.RecoveryCode == "" && req.EmailCode == "" && req.SmsCode == "" {
+		return nil, status.Error(codes.InvalidArgument, "code, recovery code, email code or sms code is required")
+	}
+
+	proof := service.MFAProof{
+		TOTPCode:     req.Code,
+		RecoveryCode: req.RecoveryCode,
+		EmailCode:    req.EmailCode,
+		SMSCode:      req.SmsCode,
+	}
+
+	tokens, err := h.svc.VerifyMFA(ctx, req.MfaToken, proof)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMFAToken):
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired mfa token")
+		case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrInvalidEmailCode), errors.Is(err, service.ErrInvalidSMSCode), errors.Is(err, service.ErrMFANotEnrolled):
+			return nil, status.Error(codes.Unauthenticated, "invalid mfa code")
+		case errors.Is(err, service.ErrEmailNotVerified):
+			return nil, status.Error(codes.FailedPrecondition, "email not verified")
+		case errors.Is(err, service.ErrMFAFactorNotAllowed):
+			return nil, status.Error(codes.FailedPrecondition, "an email code cannot be the second factor of an email login")
+		}
+
+		log.Printf("ERROR: AuthHandler.VerifyMFA failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return loginResponse(&service.LoginResult{Tokens: tokens}), nil
+}
+
+func (h *AuthHandler) EnrollTOTP(ctx context.Context, req *authpb.EnrollTOTPRequest) (*authpb.EnrollTOTPResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	enrollment, err := h.mfa.EnrollTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+			return nil, status.Error(codes.AlreadyExists, "two-factor authentication is already enabled")
+		}
+
+		log.Printf("ERROR: AuthHandler.EnrollTOTP failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.EnrollTOTPResponse{
+		Secret:     enrollment.Secret,
+		OtpauthUri: enrollment.URI,
+	}, nil
+}
+
+func (h *AuthHandler) ConfirmTOTP(ctx context.Context, req *authpb.ConfirmTOTPRequest) (*authpb.ConfirmTOTPResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := validate.Var(req.Code, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	recoveryCodes, err := h.mfa.ConfirmTOTP(ctx, userID, req.Code)
+	if err != nil {
+		if st := mfaStatusError(err); st != nil {
+			return nil, st
+		}
+
+		log.Printf("ERROR: AuthHandler.ConfirmTOTP failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.ConfirmTOTPResponse{
+		Message:       "Two-factor authentication enabled",
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (h *AuthHandler) DisableTOTP(ctx context.Context, req *authpb.DisableTOTPRequest) (*authpb.DisableTOTPResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := validate.Var(req.Code, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	err := h.mfa.DisableTOTP(ctx, userID, req.Code)
+	if err != nil {
+		if st := mfaStatusError(err); st != nil {
+			return nil, st
+		}
+
+		log.Printf("ERROR: AuthHandler.DisableTOTP failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.DisableTOTPResponse{
+		Message: "Two-factor authentication disabled",
+	}, nil
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(ctx context.Context, req *authpb.RegenerateRecoveryCodesRequest) (*authpb.RegenerateRecoveryCodesResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := validate.Var(req.Code, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	recoveryCodes, err := h.mfa.RegenerateRecoveryCodes(ctx, userID, req.Code)
+	if err != nil {
+		if st := mfaStatusError(err); st != nil {
+			return nil, st
+		}
+
+		log.Printf("ERROR: AuthHandler.RegenerateRecoveryCodes failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RegenerateRecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// mfaStatusError maps the MFA service errors a client can act on to gRPC
+// statuses. It returns nil for anything else.
+func mfaStatusError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		return status.Error(codes.InvalidArgument, "invalid code")
+	case errors.Is(err, service.ErrMFANotEnrolled):
+		return status.Error(codes.FailedPrecondition, "two-factor authentication is not enrolled")
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		return status.Error(codes.AlreadyExists, "two-factor authentication is already enabled")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/interceptor"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (h *AuthHandler) BeginPasskeyRegistration(ctx context.Context, req *authpb.BeginPasskeyRegistrationRequest) (*authpb.BeginPasskeyRegistrationResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	ceremony, err := h.passkeys.BeginRegistration(ctx, userID)
+	if err != nil {
+		log.Printf("ERROR: AuthHandler.BeginPasskeyRegistration failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.BeginPasskeyRegistrationResponse{
+		OptionsJson:  string(ceremony.Options),
+		SessionToken: ceremony.SessionToken,
+	}, nil
+}
+
+func (h *AuthHandler) FinishPasskeyRegistration(ctx context.Context, req *authpb.FinishPasskeyRegistrationRequest) (*authpb.FinishPasskeyRegistrationResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := validate.Var(req.SessionToken, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "session token is required")
+	}
+	if err := validate.Var(req.CredentialJson, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "credential is required")
+	}
+
+	_, err := h.passkeys.FinishRegistration(ctx, userID, req.SessionToken, req.Name, []byte(req.CredentialJson))
+	if err != nil {
+		if st := passkeyStatusError(err); st != nil {
+			return nil, st
+		}
+		if errors.Is(err, repository.ErrUniqueConstraint) {
+			return nil, status.Error(codes.AlreadyExists, "passkey already registered")
+		}
+
+		log.Printf("ERROR: AuthHandler.FinishPasskeyRegistration failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.FinishPasskeyRegistrationResponse{
+		Message: "Passkey registered successfully",
+	}, nil
+}
+
+func (h *AuthHandler) BeginPasskeyLogin(ctx context.Context, req *authpb.BeginPasskeyLoginRequest) (*authpb.BeginPasskeyLoginResponse, error) {
+	ceremony, err := h.svc.BeginPasskeyLogin(ctx, req.MfaToken)
+	if err != nil {
+		if st := passkeyStatusError(err); st != nil {
+			return nil, st
+		}
+		if errors.Is(err, service.ErrMFANotEnrolled) {
+			return nil, status.Error(codes.FailedPrecondition, "no passkey registered")
+		}
+
+		log.Printf("ERROR: AuthHandler.BeginPasskeyLogin failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.BeginPasskeyLoginResponse{
+		OptionsJson:  string(ceremony.Options),
+		SessionToken: ceremony.SessionToken,
+	}, nil
+}
+
+func (h *AuthHandler) FinishPasskeyLogin(ctx context.Context, req *authpb.FinishPasskeyLoginRequest) (*authpb.LoginResponse, error) {
+	if err := validate.Var(req.SessionToken, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "session token is required")
+	}
+	if err := validate.Var(req.CredentialJson, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "credential is required")
+	}
+
+	client := clientinfo.FromContext(ctx)
+	client.Device = req.DeviceName
+
+	tokens, err := h.svc.FinishPasskeyLogin(ctx, req.SessionToken, []byte(req.CredentialJson), client)
+	if err != nil {
+		if st := passkeyStatusError(err); st != nil {
+			return nil, st
+		}
+
+		log.Printf("ERROR: AuthHandler.FinishPasskeyLogin failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return loginResponse(&service.LoginResult{Tokens: tokens}), nil
+}
+
+func passkeyStatusError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidPasskey), errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.Unauthenticated, "invalid passkey")
+	case errors.Is(err, service.ErrInvalidPasskeySession):
+		return status.Error(codes.Unauthenticated, "invalid or expired passkey session")
+	case errors.Is(err, service.ErrInvalidMFAToken):
+		return status.Error(codes.Unauthenticated, "invalid or expired mfa token")
+	case errors.Is(err, service.ErrEmailNotVerified):
+		return status.Error(codes.FailedPrecondition, "email not verified")
+	}
+	return nil
+}
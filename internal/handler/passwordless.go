@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (h *AuthHandler) RequestMagicLink(ctx context.Context, req *authpb.RequestMagicLinkRequest) (*authpb.RequestMagicLinkResponse, error) {
+	if err := validate.Var(req.Email, "required,email"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email format")
+	}
+
+	if err := h.svc.RequestMagicLink(ctx, req.Email); err != nil {
+		log.Printf("ERROR: AuthHandler.RequestMagicLink failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RequestMagicLinkResponse{
+		Message: "If the email exists in our database, a login link was sent.",
+	}, nil
+}
+
+func (h *AuthHandler) ConsumeMagicLink(ctx context.Context, req *authpb.ConsumeMagicLinkRequest) (*authpb.LoginResponse, error) {
+	if err := validate.Var(req.Token, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	client := clientinfo.FromContext(ctx)
+	client.Device = req.DeviceName
+
+	result, err := h.svc.ConsumeMagicLink(ctx, req.Token, client)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMagicLink) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired link")
+		}
+
+		log.Printf("ERROR: AuthHandler.ConsumeMagicLink failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return loginResponse(result), nil
+}
+
+func (h *AuthHandler) RequestLoginCode(ctx context.Context, req *authpb.RequestLoginCodeRequest) (*authpb.RequestLoginCodeResponse, error) {
+	if err := validate.Var(req.Email, "required,email"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email format")
+	}
+
+	if err := h.svc.RequestLoginCode(ctx, req.Email); err != nil {
+		log.Printf("ERROR: AuthHandler.RequestLoginCode failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RequestLoginCodeResponse{
+		Message: "If the email exists in our database, a login code was sent.",
+	}, nil
+}
+
+func (h *AuthHandler) VerifyLoginCode(ctx context.Context, req *authpb.VerifyLoginCodeRequest) (*authpb.LoginResponse, error) {
+	if err := validate.Var(req.Email, "required,email"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email format")
+	}
+	if err := validate.Var(req.Code, "required,numeric"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	client := clientinfo.FromContext(ctx)
+	client.Device = req.DeviceName
+
+	result, err := h.svc.VerifyLoginCode(ctx, req.Email, req.Code, client)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidEmailCode):
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired code")
+		case errors.Is(err, service.ErrEmailNotVerified):
+			return nil, status.Error(codes.FailedPrecondition, "email not verified")
+		}
+
+		log.Printf("ERROR: AuthHandler.VerifyLoginCode failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return loginResponse(result), nil
+}
+
+func (h *AuthHandler) RequestMFAEmailCode(ctx context.Context, req *authpb.RequestMFAEmailCodeRequest) (*authpb.RequestMFAEmailCodeResponse, error) {
+	if err := validate.Var(req.MfaToken, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "mfa token is required")
+	}
+
+	err := h.svc.RequestMFAEmailCode(ctx, req.MfaToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMFAToken):
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired mfa token")
+		case errors.Is(err, service.ErrEmailNotVerified):
+			return nil, status.Error(codes.FailedPrecondition, "email not verified")
+		case errors.Is(err, service.ErrMFAFactorNotAllowed):
+			return nil, status.Error(codes.FailedPrecondition, "an email code cannot be the second factor of an email login")
+		}
+
+		log.Printf("ERROR: AuthHandler.RequestMFAEmailCode failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RequestMFAEmailCodeResponse{
+		Message: "A login code was sent to your email.",
+	}, nil
+}
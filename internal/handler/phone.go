@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/interceptor"
+	"github.com/eduardovfaleiro/gatekeeper/internal/service"
+	authpb "github.com/eduardovfaleiro/gatekeeper/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (h *AuthHandler) RequestPhoneVerification(ctx context.Context, req *authpb.RequestPhoneVerificationRequest) (*authpb.RequestPhoneVerificationResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	err := h.svc.RequestPhoneVerification(ctx, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrNoPhoneNumber) {
+			return nil, status.Error(codes.FailedPrecondition, "no phone number on the account")
+		}
+
+		log.Printf("ERROR: AuthHandler.RequestPhoneVerification failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RequestPhoneVerificationResponse{
+		Message: "A verification code was sent to your phone.",
+	}, nil
+}
+
+func (h *AuthHandler) VerifyPhone(ctx context.Context, req *authpb.VerifyPhoneRequest) (*authpb.VerifyPhoneResponse, error) {
+	userID, ok := interceptor.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	if err := validate.Var(req.Code, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	err := h.svc.VerifyPhone(ctx, userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSMSCode):
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired code")
+		case errors.Is(err, service.ErrNoPhoneNumber):
+			return nil, status.Error(codes.FailedPrecondition, "no phone number on the account")
+		}
+
+		log.Printf("ERROR: AuthHandler.VerifyPhone failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.VerifyPhoneResponse{
+		Message: "Phone number verified successfully",
+	}, nil
+}
+
+func (h *AuthHandler) RequestMFASMSCode(ctx context.Context, req *authpb.RequestMFASMSCodeRequest) (*authpb.RequestMFASMSCodeResponse, error) {
+	if err := validate.Var(req.MfaToken, "required"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "mfa token is required")
+	}
+
+	err := h.svc.RequestMFASMSCode(ctx, req.MfaToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMFAToken):
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired mfa token")
+		case errors.Is(err, service.ErrPhoneNotVerified):
+			return nil, status.Error(codes.FailedPrecondition, "phone number not verified")
+		}
+
+		log.Printf("ERROR: AuthHandler.RequestMFASMSCode failure: %v", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &authpb.RequestMFASMSCodeResponse{
+		Message: "A login code was sent to your phone.",
+	}, nil
+}
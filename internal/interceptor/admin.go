@@ -0,0 +1,48 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const adminServicePrefix = "/auth.AdminService/"
+
+// AdminInterceptor guards AdminService with a static API key sent in the
+// x-admin-key metadata. When apiKey is empty every admin call is refused.
+func AdminInterceptor(apiKey string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !isAdminMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		if apiKey == "" {
+			return nil, status.Error(codes.PermissionDenied, "admin api is disabled")
+		}
+
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "metadata is missing")
+		}
+
+		keyHeader := md.Get("x-admin-key")
+		if len(keyHeader) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "admin key is required")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(keyHeader[0]), []byte(apiKey)) != 1 {
+			return nil, status.Error(codes.PermissionDenied, "invalid admin key")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func isAdminMethod(method string) bool {
+	return strings.HasPrefix(method, adminServicePrefix)
+}
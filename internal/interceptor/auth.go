@@ -2,8 +2,12 @@ package interceptor
 
 import (
 	"context"
+	"errors"
+	"log"
 	"strings"
 
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
 	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -11,9 +15,15 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func AuthInterceptor(secret string) grpc.UnaryServerInterceptor {
+// TokenVersions returns the current token version of a user. Access tokens
+// carrying an older version are rejected.
+type TokenVersions interface {
+	GetTokenVersion(ctx context.Context, userID model.ID) (int, error)
+}
+
+func AuthInterceptor(keys token.KeySet, revocations repository.TokenRevocationRepository, versions TokenVersions) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		if isPublicMethod(info.FullMethod) {
+		if isPublicMethod(info.FullMethod) || isAdminMethod(info.FullMethod) {
 			return handler(ctx, req)
 		}
 
@@ -29,22 +39,69 @@ func AuthInterceptor(secret string) grpc.UnaryServerInterceptor {
 
 		tokenStr := strings.TrimPrefix(authHeader[0], "Bearer ")
 
-		userID, err := token.ValidateToken(tokenStr, secret)
+		claims, err := token.ValidateToken(tokenStr, keys)
 		if err != nil {
 			return nil, status.Error(codes.Unauthenticated, "invalid token")
 		}
 
-		newCtx := context.WithValue(ctx, "user_id", userID)
+		revoked, err := revocations.IsRevoked(ctx, claims.TokenID, claims.SessionID, claims.UserID, claims.IssuedAt)
+		if err != nil {
+			log.Printf("ERROR: AuthInterceptor revocation check: %v", err)
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+		if revoked {
+			return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+		}
+
+		version, err := versions.GetTokenVersion(ctx, claims.UserID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+			}
+			log.Printf("ERROR: AuthInterceptor token version check: %v", err)
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+		if claims.TokenVersion < version {
+			return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+		}
+
+		newCtx := context.WithValue(ctx, "user_id", claims.UserID)
+		newCtx = context.WithValue(newCtx, "token_claims", claims)
 
 		return handler(newCtx, req)
 	}
 }
 
+func UserIDFromContext(ctx context.Context) (model.ID, bool) {
+	userID, ok := ctx.Value("user_id").(model.ID)
+	return userID, ok
+}
+
+func ClaimsFromContext(ctx context.Context) (*token.Claims, bool) {
+	claims, ok := ctx.Value("token_claims").(*token.Claims)
+	return claims, ok
+}
+
 var publicMethods = map[string]struct{}{
-	"/auth.AuthService/Login":          {},
-	"/auth.AuthService/Register":       {},
-	"/auth.AuthService/ForgotPassword": {},
-	"/auth.AuthService/ResetPassword":  {},
+	"/auth.AuthService/Login":                   {},
+	"/auth.AuthService/Register":                {},
+	"/auth.AuthService/ForgotPassword":          {},
+	"/auth.AuthService/ResetPassword":           {},
+	"/auth.AuthService/RefreshToken":            {},
+	"/auth.AuthService/GetJWKS":                 {},
+	"/auth.AuthService/VerifyMFA":               {},
+	"/auth.AuthService/BeginPasskeyLogin":       {},
+	"/auth.AuthService/FinishPasskeyLogin":      {},
+	"/auth.AuthService/VerifyEmail":             {},
+	"/auth.AuthService/ResendVerificationEmail": {},
+	"/auth.AuthService/RequestMagicLink":        {},
+	"/auth.AuthService/ConsumeMagicLink":        {},
+	"/auth.AuthService/RequestLoginCode":        {},
+	"/auth.AuthService/VerifyLoginCode":         {},
+	"/auth.AuthService/RequestMFAEmailCode":     {},
+	"/auth.AuthService/ConfirmEmailChange":      {},
+	"/auth.AuthService/UndoEmailChange":         {},
+	"/auth.AuthService/RequestMFASMSCode":       {},
 }
 
 func isPublicMethod(method string) bool {
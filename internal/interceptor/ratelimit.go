@@ -0,0 +1,187 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Values for RateLimit.By other than these name a string field of the
+// request message, such as "email".
+const (
+	RateLimitByIP   = "ip"
+	RateLimitByUser = "user"
+)
+
+// RateLimit allows Limit calls per Window for each distinct key.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+	By     string
+}
+
+// slidingWindowScript keeps the timestamps of recent calls in a sorted set.
+// It returns 0 when the call is allowed, or the milliseconds until the oldest
+// call leaves the window.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+
+if redis.call('ZCARD', key) < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+	return 0
+end
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+return math.max(tonumber(oldest[2]) + window - now, 1)
+`)
+
+// RateLimitInterceptor applies sliding window limits to the methods in
+// limits, keyed by full method name; a call must pass every limit of its
+// method. It must run after AuthInterceptor so that limits by user can see
+// the caller. Limits without a usable key, and calls made while Redis is
+// unavailable, are let through.
+func RateLimitInterceptor(rdb *redis.Client, limits map[string][]RateLimit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		for _, limit := range limits[info.FullMethod] {
+			if limit.Limit <= 0 {
+				continue
+			}
+
+			subject := rateLimitSubject(ctx, req, limit.By)
+			if subject == "" {
+				continue
+			}
+
+			// Subjects can be bearer tokens or email addresses, which do not
+			// belong in key names.
+			key := fmt.Sprintf("rate_limit:%s:%s:%s:%s", info.FullMethod, limit.By, limit.Window, token.HashOpaqueToken(subject))
+
+			wait, err := slidingWindowScript.Run(ctx, rdb, []string{key},
+				time.Now().UnixMilli(), limit.Window.Milliseconds(), limit.Limit, uuid.New().String(),
+			).Int64()
+			if err != nil {
+				log.Printf("ERROR: RateLimitInterceptor: %v", err)
+				continue
+			}
+
+			if wait > 0 {
+				return nil, rateLimitError(time.Duration(wait) * time.Millisecond)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func rateLimitSubject(ctx context.Context, req any, by string) string {
+	switch by {
+	case RateLimitByIP:
+		return clientinfo.IP(ctx)
+	case RateLimitByUser:
+		if userID, ok := UserIDFromContext(ctx); ok {
+			return userID.String()
+		}
+		return ""
+	}
+
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return ""
+	}
+
+	m := msg.ProtoReflect()
+	field := m.Descriptor().Fields().ByName(protoreflect.Name(by))
+	if field == nil || field.Kind() != protoreflect.StringKind || field.IsList() {
+		return ""
+	}
+
+	value := m.Get(field).String()
+
+	// Email addresses are case-insensitive; tokens and codes are not.
+	if strings.HasSuffix(string(field.Name()), "email") {
+		value = strings.ToLower(strings.TrimSpace(value))
+	}
+
+	return value
+}
+
+func rateLimitError(retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryAfter.Round(time.Second)),
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// ParseRateLimits reads limits written as
+// "<full method>=<limit>/<window>@<key>" and separated by commas, for example
+// "/auth.AuthService/Register=5/1h@ip". A method may be listed more than once.
+func ParseRateLimits(s string) (map[string][]RateLimit, error) {
+	limits := make(map[string][]RateLimit)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		method, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q: missing '='", entry)
+		}
+
+		spec, by, ok := strings.Cut(spec, "@")
+		if !ok || by == "" {
+			return nil, fmt.Errorf("rate limit %q: missing '@<key>'", entry)
+		}
+
+		count, window, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q: missing '/<window>'", entry)
+		}
+
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit %q: %w", entry, err)
+		}
+
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit %q: %w", entry, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("rate limit %q: window must be positive", entry)
+		}
+
+		method = strings.TrimSpace(method)
+		limits[method] = append(limits[method], RateLimit{Limit: n, Window: d, By: by})
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type PasswordHistoryEntry struct {
+	ID           ID        `json:"id" db:"id"`
+	UserID       ID        `json:"user_id" db:"user_id"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
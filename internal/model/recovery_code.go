@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type RecoveryCode struct {
+	ID     ID `json:"id" db:"id"`
+	UserID ID `json:"user_id" db:"user_id"`
+	// CodePrefix is the first group of the code, kept in clear to find the
+	// hash to check without trying every code of the user.
+	CodePrefix string     `json:"-" db:"code_prefix"`
+	CodeHash   string     `json:"-" db:"code_hash"`
+	UsedAt     *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	Hash      string     `json:"-"`
+	UserID    ID         `json:"user_id"`
+	FamilyID  ID         `json:"family_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+}
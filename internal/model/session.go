@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type Session struct {
+	ID         ID         `json:"id" db:"id"`
+	UserID     ID         `json:"user_id" db:"user_id"`
+	Device     string     `json:"device" db:"device"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+const (
+	SigningKeyPending  = "pending"
+	SigningKeyActive   = "active"
+	SigningKeyRetiring = "retiring"
+	SigningKeyRetired  = "retired"
+)
+
+type SigningKey struct {
+	ID         string     `json:"kid" db:"kid"`
+	Algorithm  string     `json:"alg" db:"algorithm"`
+	PrivateKey []byte     `json:"-" db:"private_key"`
+	State      string     `json:"state" db:"state"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	RetiringAt *time.Time `json:"retiring_at,omitempty" db:"retiring_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty" db:"retired_at"`
+}
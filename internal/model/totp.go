@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type TOTP struct {
+	UserID      ID         `json:"user_id" db:"user_id"`
+	Secret      []byte     `json:"-" db:"secret"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
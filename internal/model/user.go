@@ -5,8 +5,16 @@ import (
 )
 
 type User struct {
-	ID           ID        `json:"id" db:"id"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"`
+	ID       ID      `json:"id" db:"id"`
+	Email    string  `json:"email" db:"email"`
+	Username *string `json:"username,omitempty" db:"username"`
+	// PhoneNumber is in E.164 format, such as +5511987654321.
+	PhoneNumber     *string    `json:"phone_number,omitempty" db:"phone_number"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty" db:"phone_verified_at"`
+	PasswordHash    string     `json:"-" db:"password_hash"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	// TokenVersion is carried in access tokens; raising it invalidates every
+	// token issued before.
+	TokenVersion int       `json:"-" db:"token_version"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type WebAuthnCredential struct {
+	ID              []byte     `json:"id" db:"id"`
+	UserID          ID         `json:"user_id" db:"user_id"`
+	Name            string     `json:"name" db:"name"`
+	PublicKey       []byte     `json:"-" db:"public_key"`
+	AttestationType string     `json:"attestation_type" db:"attestation_type"`
+	Transports      []string   `json:"transports" db:"transports"`
+	AAGUID          []byte     `json:"aaguid" db:"aaguid"`
+	SignCount       uint32     `json:"sign_count" db:"sign_count"`
+	BackupEligible  bool       `json:"backup_eligible" db:"backup_eligible"`
+	BackupState     bool       `json:"backup_state" db:"backup_state"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
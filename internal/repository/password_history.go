@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+)
+
+type PasswordHistoryRepository interface {
+	// Add stores entry and deletes all but the newest keep entries of the user.
+	Add(ctx context.Context, entry *model.PasswordHistoryEntry, keep int) error
+	// ListRecent returns the newest limit hashes of the user, newest first.
+	ListRecent(ctx context.Context, userID model.ID, limit int) ([]string, error)
+}
+
+type postgresPasswordHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPasswordHistoryRepository(db *sql.DB) PasswordHistoryRepository {
+	return &postgresPasswordHistoryRepository{db}
+}
+
+func (r *postgresPasswordHistoryRepository) Add(ctx context.Context, entry *model.PasswordHistoryEntry, keep int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository.PasswordHistory.Add (begin): %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO password_history (id, user_id, password_hash, created_at) VALUES ($1, $2, $3, $4)`
+
+	if _, err := tx.ExecContext(ctx, query, entry.ID, entry.UserID, entry.PasswordHash, entry.CreatedAt); err != nil {
+		return fmt.Errorf("repository.PasswordHistory.Add (insert): %w", err)
+	}
+
+	prune := `DELETE FROM password_history WHERE user_id = $1 AND id NOT IN (
+		SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
+	)`
+
+	if _, err := tx.ExecContext(ctx, prune, entry.UserID, keep); err != nil {
+		return fmt.Errorf("repository.PasswordHistory.Add (prune): %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository.PasswordHistory.Add (commit): %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresPasswordHistoryRepository) ListRecent(ctx context.Context, userID model.ID, limit int) ([]string, error) {
+	query := `SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository.PasswordHistory.ListRecent (query): %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var passwordHash string
+		if err := rows.Scan(&passwordHash); err != nil {
+			return nil, fmt.Errorf("repository.PasswordHistory.ListRecent (scan): %w", err)
+		}
+		hashes = append(hashes, passwordHash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository.PasswordHistory.ListRecent (rows): %w", err)
+	}
+
+	return hashes, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+)
+
+type RecoveryCodeRepository interface {
+	// Replace deletes every code of the user and stores the new set.
+	Replace(ctx context.Context, userID model.ID, codes []*model.RecoveryCode) error
+	// ListUnused returns the user's unused codes starting with prefix.
+	ListUnused(ctx context.Context, userID model.ID, prefix string) ([]*model.RecoveryCode, error)
+	// MarkUsed returns ErrNotFound if the code was already used.
+	MarkUsed(ctx context.Context, id model.ID, usedAt time.Time) error
+	DeleteAll(ctx context.Context, userID model.ID) error
+}
+
+type postgresRecoveryCodeRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRecoveryCodeRepository(db *sql.DB) RecoveryCodeRepository {
+	return &postgresRecoveryCodeRepository{db}
+}
+
+func (r *postgresRecoveryCodeRepository) Replace(ctx context.Context, userID model.ID, codes []*model.RecoveryCode) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository.RecoveryCode.Replace (begin): %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("repository.RecoveryCode.Replace (delete): %w", err)
+	}
+
+	query := `INSERT INTO mfa_recovery_codes (id, user_id, code_prefix, code_hash, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	for _, code := range codes {
+		if _, err := tx.ExecContext(ctx, query, code.ID, code.UserID, code.CodePrefix, code.CodeHash, code.CreatedAt); err != nil {
+			return fmt.Errorf("repository.RecoveryCode.Replace (insert): %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository.RecoveryCode.Replace (commit): %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresRecoveryCodeRepository) ListUnused(ctx context.Context, userID model.ID, prefix string) ([]*model.RecoveryCode, error) {
+	query := `SELECT id, user_id, code_prefix, code_hash, used_at, created_at FROM mfa_recovery_codes
+		WHERE user_id = $1 AND code_prefix = $2 AND used_at IS NULL`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("repository.RecoveryCode.ListUnused (query): %w", err)
+	}
+	defer rows.Close()
+
+	var codes []*model.RecoveryCode
+	for rows.Next() {
+		var code model.RecoveryCode
+		if err := rows.Scan(&code.ID, &code.UserID, &code.CodePrefix, &code.CodeHash, &code.UsedAt, &code.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repository.RecoveryCode.ListUnused (scan): %w", err)
+		}
+		codes = append(codes, &code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository.RecoveryCode.ListUnused (rows): %w", err)
+	}
+
+	return codes, nil
+}
+
+func (r *postgresRecoveryCodeRepository) MarkUsed(ctx context.Context, id model.ID, usedAt time.Time) error {
+	query := `UPDATE mfa_recovery_codes SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, usedAt, id)
+	if err != nil {
+		return fmt.Errorf("repository.RecoveryCode.MarkUsed (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.RecoveryCode.MarkUsed (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresRecoveryCodeRepository) DeleteAll(ctx context.Context, userID model.ID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("repository.RecoveryCode.DeleteAll (exec): %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*model.RefreshToken, error)
+	// MarkRotated flags the token as used. It returns false if the token had
+	// already been rotated, which means it is being replayed, and ErrNotFound
+	// if the token has expired in the meantime.
+	MarkRotated(ctx context.Context, hash string) (bool, error)
+	IsFamilyActive(ctx context.Context, familyID model.ID) (bool, error)
+	RevokeFamily(ctx context.Context, familyID model.ID) error
+	RevokeUserFamilies(ctx context.Context, userID model.ID) error
+}
+
+type redisRefreshTokenRepository struct {
+	redis *redis.Client
+}
+
+func NewRedisRefreshTokenRepository(redis *redis.Client) RefreshTokenRepository {
+	return &redisRefreshTokenRepository{redis}
+}
+
+func refreshTokenKey(hash string) string {
+	return fmt.Sprintf("refresh_token:%s", hash)
+}
+
+func refreshFamilyKey(familyID model.ID) string {
+	return fmt.Sprintf("refresh_family:%s", familyID)
+}
+
+func userRefreshFamiliesKey(userID model.ID) string {
+	return fmt.Sprintf("refresh_families:%s", userID)
+}
+
+func (r *redisRefreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("repository.RefreshToken.Create: token already expired")
+	}
+
+	tokenKey := refreshTokenKey(token.Hash)
+
+	pipe := r.redis.TxPipeline()
+	pipe.HSet(ctx, tokenKey, map[string]any{
+		"user_id":    token.UserID.String(),
+		"family_id":  token.FamilyID.String(),
+		"created_at": token.CreatedAt.Unix(),
+		"expires_at": token.ExpiresAt.Unix(),
+	})
+	pipe.Expire(ctx, tokenKey, ttl)
+	pipe.Set(ctx, refreshFamilyKey(token.FamilyID), token.UserID.String(), ttl)
+	pipe.SAdd(ctx, userRefreshFamiliesKey(token.UserID), token.FamilyID.String())
+	pipe.Expire(ctx, userRefreshFamiliesKey(token.UserID), ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("repository.RefreshToken.Create (exec): %w", err)
+	}
+
+	return nil
+}
+
+func (r *redisRefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	fields, err := r.redis.HGetAll(ctx, refreshTokenKey(hash)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("repository.RefreshToken.GetByHash (hgetall): %w", err)
+	}
+
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+
+	token := &model.RefreshToken{Hash: hash}
+
+	if token.UserID, err = model.ParseID(fields["user_id"]); err != nil {
+		return nil, fmt.Errorf("repository.RefreshToken.GetByHash (user_id): %w", err)
+	}
+	if token.FamilyID, err = model.ParseID(fields["family_id"]); err != nil {
+		return nil, fmt.Errorf("repository.RefreshToken.GetByHash (family_id): %w", err)
+	}
+	if token.CreatedAt, err = parseUnix(fields["created_at"]); err != nil {
+		return nil, fmt.Errorf("repository.RefreshToken.GetByHash (created_at): %w", err)
+	}
+	if token.ExpiresAt, err = parseUnix(fields["expires_at"]); err != nil {
+		return nil, fmt.Errorf("repository.RefreshToken.GetByHash (expires_at): %w", err)
+	}
+	if v, ok := fields["rotated_at"]; ok {
+		rotatedAt, err := parseUnix(v)
+		if err != nil {
+			return nil, fmt.Errorf("repository.RefreshToken.GetByHash (rotated_at): %w", err)
+		}
+		token.RotatedAt = &rotatedAt
+	}
+
+	return token, nil
+}
+
+// markRotatedScript sets rotated_at only on a token that still exists, so an
+// expired token is not recreated without a TTL. It returns -1 for a missing
+// token, otherwise the result of HSETNX.
+var markRotatedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+return redis.call('HSETNX', KEYS[1], 'rotated_at', ARGV[1])
+`)
+
+func (r *redisRefreshTokenRepository) MarkRotated(ctx context.Context, hash string) (bool, error) {
+	n, err := markRotatedScript.Run(ctx, r.redis, []string{refreshTokenKey(hash)}, model.NewTimestamp().Unix()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("repository.RefreshToken.MarkRotated (script): %w", err)
+	}
+
+	if n < 0 {
+		return false, ErrNotFound
+	}
+
+	return n == 1, nil
+}
+
+func (r *redisRefreshTokenRepository) IsFamilyActive(ctx context.Context, familyID model.ID) (bool, error) {
+	n, err := r.redis.Exists(ctx, refreshFamilyKey(familyID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("repository.RefreshToken.IsFamilyActive (exists): %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (r *redisRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID model.ID) error {
+	if err := r.redis.Del(ctx, refreshFamilyKey(familyID)).Err(); err != nil {
+		return fmt.Errorf("repository.RefreshToken.RevokeFamily (del): %w", err)
+	}
+
+	return nil
+}
+
+func (r *redisRefreshTokenRepository) RevokeUserFamilies(ctx context.Context, userID model.ID) error {
+	setKey := userRefreshFamiliesKey(userID)
+
+	familyIDs, err := r.redis.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return fmt.Errorf("repository.RefreshToken.RevokeUserFamilies (smembers): %w", err)
+	}
+
+	keys := make([]string, 0, len(familyIDs)+1)
+	for _, id := range familyIDs {
+		keys = append(keys, fmt.Sprintf("refresh_family:%s", id))
+	}
+	keys = append(keys, setKey)
+
+	if err := r.redis.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("repository.RefreshToken.RevokeUserFamilies (del): %w", err)
+	}
+
+	return nil
+}
+
+func parseUnix(s string) (time.Time, error) {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	GetByID(ctx context.Context, id model.ID) (*model.Session, error)
+	ListActiveByUser(ctx context.Context, userID model.ID) ([]*model.Session, error)
+	Touch(ctx context.Context, id model.ID, lastSeenAt time.Time) error
+	Revoke(ctx context.Context, userID, id model.ID, revokedAt time.Time) error
+	RevokeAllByUser(ctx context.Context, userID model.ID, revokedAt time.Time) error
+}
+
+type postgresSessionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSessionRepository(db *sql.DB) SessionRepository {
+	return &postgresSessionRepository{db}
+}
+
+const sessionColumns = `id, user_id, device, user_agent, ip_address, created_at, last_seen_at, revoked_at`
+
+func scanSession(row interface{ Scan(...any) error }) (*model.Session, error) {
+	var s model.Session
+	err := row.Scan(&s.ID, &s.UserID, &s.Device, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastSeenAt, &s.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *postgresSessionRepository) Create(ctx context.Context, session *model.Session) error {
+	query := `INSERT INTO sessions (id, user_id, device, user_agent, ip_address, created_at, last_seen_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.ExecContext(ctx, query, session.ID, session.UserID, session.Device, session.UserAgent, session.IPAddress, session.CreatedAt, session.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("repository.Session.Create (exec): %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresSessionRepository) GetByID(ctx context.Context, id model.ID) (*model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("repository.Session.GetByID (scan): %w", err)
+	}
+
+	return session, nil
+}
+
+func (r *postgresSessionRepository) ListActiveByUser(ctx context.Context, userID model.ID) ([]*model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.Session.ListActiveByUser (query): %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository.Session.ListActiveByUser (scan): %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository.Session.ListActiveByUser (rows): %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (r *postgresSessionRepository) Touch(ctx context.Context, id model.ID, lastSeenAt time.Time) error {
+	query := `UPDATE sessions SET last_seen_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, lastSeenAt, id)
+	if err != nil {
+		return fmt.Errorf("repository.Session.Touch (exec): %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresSessionRepository) Revoke(ctx context.Context, userID, id model.ID, revokedAt time.Time) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, revokedAt, id, userID)
+	if err != nil {
+		return fmt.Errorf("repository.Session.Revoke (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.Session.Revoke (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresSessionRepository) RevokeAllByUser(ctx context.Context, userID model.ID, revokedAt time.Time) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, revokedAt, userID)
+	if err != nil {
+		return fmt.Errorf("repository.Session.RevokeAllByUser (exec): %w", err)
+	}
+
+	return nil
+}
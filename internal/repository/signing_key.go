@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/lib/pq"
+)
+
+type SigningKeyRepository interface {
+	// ListUsable returns the pending, active and retiring keys, newest first.
+	ListUsable(ctx context.Context) ([]*model.SigningKey, error)
+	// Create stores key in key.State. Only one key may be active and only one
+	// pending at a time; a second one returns ErrUniqueConstraint.
+	Create(ctx context.Context, key *model.SigningKey) error
+	// Promote activates the pending key created before pendingBefore, demotes
+	// the active key to retiring and retires keys that have been retiring
+	// since before retireBefore, all in one transaction. It reports whether a
+	// key was promoted.
+	Promote(ctx context.Context, pendingBefore, retireBefore time.Time) (bool, error)
+	RetireExpired(ctx context.Context, retireBefore time.Time) error
+}
+
+type postgresSigningKeyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSigningKeyRepository(db *sql.DB) SigningKeyRepository {
+	return &postgresSigningKeyRepository{db}
+}
+
+func (r *postgresSigningKeyRepository) ListUsable(ctx context.Context) ([]*model.SigningKey, error) {
+	query := `SELECT kid, algorithm, private_key, state, created_at, retiring_at, retired_at
+		FROM signing_keys WHERE state IN ('pending', 'active', 'retiring') ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("repository.SigningKey.ListUsable (query): %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*model.SigningKey
+	for rows.Next() {
+		var key model.SigningKey
+		err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.State, &key.CreatedAt, &key.RetiringAt, &key.RetiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("repository.SigningKey.ListUsable (scan): %w", err)
+		}
+		keys = append(keys, &key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository.SigningKey.ListUsable (rows): %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *postgresSigningKeyRepository) Create(ctx context.Context, key *model.SigningKey) error {
+	query := `INSERT INTO signing_keys (kid, algorithm, private_key, state, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, key.ID, key.Algorithm, key.PrivateKey, key.State, key.CreatedAt)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return ErrUniqueConstraint
+			}
+		}
+		return fmt.Errorf("repository.SigningKey.Create (insert): %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresSigningKeyRepository) Promote(ctx context.Context, pendingBefore, retireBefore time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("repository.SigningKey.Promote (begin): %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the pending row makes concurrent promotions wait, after which
+	// the key is no longer pending and they find nothing to do.
+	var kid string
+	err = tx.QueryRowContext(ctx, `SELECT kid FROM signing_keys WHERE state = 'pending' AND created_at < $1 FOR UPDATE`, pendingBefore).Scan(&kid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("repository.SigningKey.Promote (select): %w", err)
+	}
+
+	now := model.NewTimestamp()
+
+	_, err = tx.ExecContext(ctx, `UPDATE signing_keys SET state = 'retiring', retiring_at = $1 WHERE state = 'active'`, now)
+	if err != nil {
+		return false, fmt.Errorf("repository.SigningKey.Promote (demote): %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE signing_keys SET state = 'retired', retired_at = $1 WHERE state = 'retiring' AND retiring_at < $2`, now, retireBefore)
+	if err != nil {
+		return false, fmt.Errorf("repository.SigningKey.Promote (retire): %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE signing_keys SET state = 'active' WHERE kid = $1`, kid)
+	if err != nil {
+		return false, fmt.Errorf("repository.SigningKey.Promote (activate): %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("repository.SigningKey.Promote (commit): %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *postgresSigningKeyRepository) RetireExpired(ctx context.Context, retireBefore time.Time) error {
+	query := `UPDATE signing_keys SET state = 'retired', retired_at = $1 WHERE state = 'retiring' AND retiring_at < $2`
+
+	_, err := r.db.ExecContext(ctx, query, model.NewTimestamp(), retireBefore)
+	if err != nil {
+		return fmt.Errorf("repository.SigningKey.RetireExpired (exec): %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+type TokenRevocationRepository interface {
+	// RevokeToken blocks a single access token until it would have expired.
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// RevokeUserTokens blocks every access token of the user issued before
+	// the second of the given instant. Tokens only carry whole seconds, so
+	// those issued within that second stay valid; callers that must reject
+	// them also raise the user's token version. The entry is kept for ttl,
+	// which must be at least the access token lifetime.
+	RevokeUserTokens(ctx context.Context, userID model.ID, before time.Time, ttl time.Duration) error
+	// RevokeSession blocks every access token carrying the session id for ttl.
+	RevokeSession(ctx context.Context, sessionID model.ID, ttl time.Duration) error
+	IsRevoked(ctx context.Context, tokenID string, sessionID, userID model.ID, issuedAt time.Time) (bool, error)
+}
+
+type redisTokenRevocationRepository struct {
+	redis *redis.Client
+}
+
+func NewRedisTokenRevocationRepository(redis *redis.Client) TokenRevocationRepository {
+	return &redisTokenRevocationRepository{redis}
+}
+
+func revokedTokenKey(tokenID string) string {
+	return fmt.Sprintf("revoked_token:%s", tokenID)
+}
+
+func revokedSessionKey(sessionID model.ID) string {
+	return fmt.Sprintf("revoked_session:%s", sessionID)
+}
+
+func revokedUserTokensKey(userID model.ID) string {
+	return fmt.Sprintf("revoked_user_tokens:%s", userID)
+}
+
+func (r *redisTokenRevocationRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := r.redis.Set(ctx, revokedTokenKey(tokenID), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("repository.TokenRevocation.RevokeToken (set): %w", err)
+	}
+
+	return nil
+}
+
+func (r *redisTokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID model.ID, before time.Time, ttl time.Duration) error {
+	if err := r.redis.Set(ctx, revokedUserTokensKey(userID), before.Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("repository.TokenRevocation.RevokeUserTokens (set): %w", err)
+	}
+
+	return nil
+}
+
+func (r *redisTokenRevocationRepository) RevokeSession(ctx context.Context, sessionID model.ID, ttl time.Duration) error {
+	if err := r.redis.Set(ctx, revokedSessionKey(sessionID), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("repository.TokenRevocation.RevokeSession (set): %w", err)
+	}
+
+	return nil
+}
+
+func (r *redisTokenRevocationRepository) IsRevoked(ctx context.Context, tokenID string, sessionID, userID model.ID, issuedAt time.Time) (bool, error) {
+	values, err := r.redis.MGet(ctx, revokedTokenKey(tokenID), revokedSessionKey(sessionID), revokedUserTokensKey(userID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("repository.TokenRevocation.IsRevoked (mget): %w", err)
+	}
+
+	if values[0] != nil || values[1] != nil {
+		return true, nil
+	}
+
+	if cutoff, ok := values[2].(string); ok {
+		before, err := parseUnix(cutoff)
+		if err != nil {
+			return false, fmt.Errorf("repository.TokenRevocation.IsRevoked (cutoff): %w", err)
+		}
+		if issuedAt.Before(before) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
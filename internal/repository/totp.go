@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+)
+
+type TOTPRepository interface {
+	// Upsert stores a pending secret, replacing any earlier unconfirmed one.
+	// It returns ErrUniqueConstraint if the user already has a confirmed secret.
+	Upsert(ctx context.Context, totp *model.TOTP) error
+	GetByUserID(ctx context.Context, userID model.ID) (*model.TOTP, error)
+	Confirm(ctx context.Context, userID model.ID, confirmedAt time.Time) error
+	Delete(ctx context.Context, userID model.ID) error
+}
+
+type postgresTOTPRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTOTPRepository(db *sql.DB) TOTPRepository {
+	return &postgresTOTPRepository{db}
+}
+
+func (r *postgresTOTPRepository) Upsert(ctx context.Context, totp *model.TOTP) error {
+	query := `INSERT INTO user_totp (user_id, secret, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+		WHERE user_totp.confirmed_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, totp.UserID, totp.Secret, totp.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository.TOTP.Upsert (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.TOTP.Upsert (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUniqueConstraint
+	}
+
+	return nil
+}
+
+func (r *postgresTOTPRepository) GetByUserID(ctx context.Context, userID model.ID) (*model.TOTP, error) {
+	query := `SELECT user_id, secret, confirmed_at, created_at FROM user_totp WHERE user_id = $1`
+
+	var totp model.TOTP
+
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&totp.UserID, &totp.Secret, &totp.ConfirmedAt, &totp.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("repository.TOTP.GetByUserID (scan): %w", err)
+	}
+
+	return &totp, nil
+}
+
+func (r *postgresTOTPRepository) Confirm(ctx context.Context, userID model.ID, confirmedAt time.Time) error {
+	query := `UPDATE user_totp SET confirmed_at = $1 WHERE user_id = $2 AND confirmed_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, confirmedAt, userID)
+	if err != nil {
+		return fmt.Errorf("repository.TOTP.Confirm (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.TOTP.Confirm (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresTOTPRepository) Delete(ctx context.Context, userID model.ID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("repository.TOTP.Delete (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.TOTP.Delete (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/eduardovfaleiro/gatekeeper/internal/model"
 	"github.com/lib/pq"
@@ -13,7 +14,26 @@ import (
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByID(ctx context.Context, id model.ID) (*model.User, error)
+	// GetByIdentifier finds the user whose email, username or phone number is
+	// identifier. The formats do not overlap, so at most one user matches.
+	GetByIdentifier(ctx context.Context, identifier string) (*model.User, error)
 	UpdatePassword(ctx context.Context, userID model.ID, hashedPassword string) error
+	MarkEmailVerified(ctx context.Context, userID model.ID, verifiedAt time.Time) error
+	// MarkPhoneVerified returns ErrNotFound unless the user still has
+	// phoneNumber.
+	MarkPhoneVerified(ctx context.Context, userID model.ID, phoneNumber string, verifiedAt time.Time) error
+	// UpdateEmail returns ErrUniqueConstraint if another user has the address.
+	UpdateEmail(ctx context.Context, userID model.ID, email string, verifiedAt time.Time) error
+	// UpdateUsername sets the username, or removes it when nil. It returns
+	// ErrUniqueConstraint if another user has it.
+	UpdateUsername(ctx context.Context, userID model.ID, username *string) error
+	// UpdatePhoneNumber sets the phone number, or removes it when nil, and
+	// clears phone_verified_at if the number changes. It returns
+	// ErrUniqueConstraint if another user has it.
+	UpdatePhoneNumber(ctx context.Context, userID model.ID, phoneNumber *string) error
+	GetTokenVersion(ctx context.Context, userID model.ID) (int, error)
+	IncrementTokenVersion(ctx context.Context, userID model.ID) error
 }
 
 type postgresUserRepository struct {
@@ -21,9 +41,9 @@ type postgresUserRepository struct {
 }
 
 func (r *postgresUserRepository) Create(ctx context.Context, user *model.User) error {
-	query := `INSERT INTO users (id, email, password_hash, created_at) VALUES ($1, $2, $3, $4)`
+	query := `INSERT INTO users (id, email, username, phone_number, password_hash, email_verified_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
 
-	_, err := r.db.ExecContext(ctx, query, user.ID, user.Email, user.PasswordHash, user.CreatedAt)
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Email, user.Username, user.PhoneNumber, user.PasswordHash, user.EmailVerifiedAt, user.CreatedAt)
 
 	if err != nil {
 		if pgErr, ok := err.(*pq.Error); ok {
@@ -42,22 +62,38 @@ func NewPostgresUserRepository(db *sql.DB) UserRepository {
 	return &postgresUserRepository{db}
 }
 
-func (r *postgresUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, email, password_hash, created_at FROM users WHERE email = $1`
+const userColumns = `id, email, username, phone_number, phone_verified_at, password_hash, email_verified_at, token_version, created_at`
 
+func scanUser(row interface{ Scan(...any) error }) (*model.User, error) {
 	var user model.User
-
-	err := r.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.PhoneNumber, &user.PhoneVerifiedAt, &user.PasswordHash, &user.EmailVerifiedAt, &user.TokenVersion, &user.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-
 	return &user, nil
 }
 
+func (r *postgresUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+
+	return scanUser(r.db.QueryRowContext(ctx, query, email))
+}
+
+func (r *postgresUserRepository) GetByID(ctx context.Context, id model.ID) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	return scanUser(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *postgresUserRepository) GetByIdentifier(ctx context.Context, identifier string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1 OR username = $1 OR phone_number = $1`
+
+	return scanUser(r.db.QueryRowContext(ctx, query, identifier))
+}
+
 func (r *postgresUserRepository) UpdatePassword(ctx context.Context, userID model.ID, hashedPassword string) error {
 	query := `UPDATE users SET password_hash = $1 WHERE id = $2`
 
@@ -77,3 +113,141 @@ func (r *postgresUserRepository) UpdatePassword(ctx context.Context, userID mode
 
 	return nil
 }
+
+// MarkEmailVerified sets email_verified_at unless the address was already
+// verified, in which case the original timestamp is kept.
+func (r *postgresUserRepository) MarkEmailVerified(ctx context.Context, userID model.ID, verifiedAt time.Time) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, $1) WHERE id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, verifiedAt, userID)
+	if err != nil {
+		return fmt.Errorf("repository.MarkEmailVerified (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.MarkEmailVerified (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresUserRepository) MarkPhoneVerified(ctx context.Context, userID model.ID, phoneNumber string, verifiedAt time.Time) error {
+	query := `UPDATE users SET phone_verified_at = $1 WHERE id = $2 AND phone_number = $3`
+
+	result, err := r.db.ExecContext(ctx, query, verifiedAt, userID, phoneNumber)
+	if err != nil {
+		return fmt.Errorf("repository.MarkPhoneVerified (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.MarkPhoneVerified (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresUserRepository) UpdateEmail(ctx context.Context, userID model.ID, email string, verifiedAt time.Time) error {
+	query := `UPDATE users SET email = $1, email_verified_at = $2 WHERE id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, email, verifiedAt, userID)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return ErrUniqueConstraint
+			}
+		}
+
+		return fmt.Errorf("repository.UpdateEmail (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.UpdateEmail (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresUserRepository) UpdateUsername(ctx context.Context, userID model.ID, username *string) error {
+	return r.updateIdentifier(ctx, "UpdateUsername", `UPDATE users SET username = $1 WHERE id = $2`, userID, username)
+}
+
+func (r *postgresUserRepository) UpdatePhoneNumber(ctx context.Context, userID model.ID, phoneNumber *string) error {
+	return r.updateIdentifier(ctx, "UpdatePhoneNumber", `UPDATE users
+		SET phone_verified_at = CASE WHEN phone_number IS NOT DISTINCT FROM $1 THEN phone_verified_at END, phone_number = $1
+		WHERE id = $2`, userID, phoneNumber)
+}
+
+func (r *postgresUserRepository) updateIdentifier(ctx context.Context, method, query string, userID model.ID, value *string) error {
+	result, err := r.db.ExecContext(ctx, query, value, userID)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return ErrUniqueConstraint
+			}
+		}
+
+		return fmt.Errorf("repository.%s (exec): %w", method, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.%s (rows_affected): %w", method, err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresUserRepository) GetTokenVersion(ctx context.Context, userID model.ID) (int, error) {
+	query := `SELECT token_version FROM users WHERE id = $1`
+
+	var version int
+
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("repository.GetTokenVersion (scan): %w", err)
+	}
+
+	return version, nil
+}
+
+func (r *postgresUserRepository) IncrementTokenVersion(ctx context.Context, userID model.ID) error {
+	query := `UPDATE users SET token_version = token_version + 1 WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("repository.IncrementTokenVersion (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.IncrementTokenVersion (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
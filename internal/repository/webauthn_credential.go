@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/lib/pq"
+)
+
+type WebAuthnCredentialRepository interface {
+	Create(ctx context.Context, credential *model.WebAuthnCredential) error
+	ListByUser(ctx context.Context, userID model.ID) ([]*model.WebAuthnCredential, error)
+	UpdateAfterLogin(ctx context.Context, credential *model.WebAuthnCredential, usedAt time.Time) error
+}
+
+type postgresWebAuthnCredentialRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresWebAuthnCredentialRepository(db *sql.DB) WebAuthnCredentialRepository {
+	return &postgresWebAuthnCredentialRepository{db}
+}
+
+func (r *postgresWebAuthnCredentialRepository) Create(ctx context.Context, c *model.WebAuthnCredential) error {
+	query := `INSERT INTO webauthn_credentials
+		(id, user_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := r.db.ExecContext(ctx, query, c.ID, c.UserID, c.Name, c.PublicKey, c.AttestationType, pq.Array(c.Transports),
+		c.AAGUID, int64(c.SignCount), c.BackupEligible, c.BackupState, c.CreatedAt)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return ErrUniqueConstraint
+			}
+		}
+
+		return fmt.Errorf("repository.WebAuthnCredential.Create (exec): %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresWebAuthnCredentialRepository) ListByUser(ctx context.Context, userID model.ID) ([]*model.WebAuthnCredential, error) {
+	query := `SELECT id, user_id, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, created_at, last_used_at
+		FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.WebAuthnCredential.ListByUser (query): %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []*model.WebAuthnCredential
+	for rows.Next() {
+		var (
+			c         model.WebAuthnCredential
+			signCount int64
+		)
+
+		err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.PublicKey, &c.AttestationType, pq.Array(&c.Transports),
+			&c.AAGUID, &signCount, &c.BackupEligible, &c.BackupState, &c.CreatedAt, &c.LastUsedAt)
+		if err != nil {
+			return nil, fmt.Errorf("repository.WebAuthnCredential.ListByUser (scan): %w", err)
+		}
+
+		c.SignCount = uint32(signCount)
+		credentials = append(credentials, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository.WebAuthnCredential.ListByUser (rows): %w", err)
+	}
+
+	return credentials, nil
+}
+
+func (r *postgresWebAuthnCredentialRepository) UpdateAfterLogin(ctx context.Context, c *model.WebAuthnCredential, usedAt time.Time) error {
+	query := `UPDATE webauthn_credentials SET sign_count = $1, backup_state = $2, last_used_at = $3 WHERE id = $4`
+
+	result, err := r.db.ExecContext(ctx, query, int64(c.SignCount), c.BackupState, usedAt, c.ID)
+	if err != nil {
+		return fmt.Errorf("repository.WebAuthnCredential.UpdateAfterLogin (exec): %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository.WebAuthnCredential.UpdateAfterLogin (rows_affected): %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
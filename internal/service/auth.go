@@ -5,40 +5,155 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
+	"strings"
 	"time"
 
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
 	"github.com/eduardovfaleiro/gatekeeper/internal/model"
 	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/internal/validation"
 	"github.com/eduardovfaleiro/gatekeeper/pkg/hash"
 	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
-	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
-	passwordValidator "github.com/wagslane/go-password-validator"
 )
 
 type AuthService interface {
-	Register(ctx context.Context, email, password string) (*model.User, error)
-	Login(ctx context.Context, email, password string) (string, error)
+	Register(ctx context.Context, email, password string, identifiers Identifiers) (*model.User, error)
+	// Login accepts an email address, username or phone number as identifier.
+	Login(ctx context.Context, identifier, password string, client clientinfo.Info) (*LoginResult, error)
+	VerifyMFA(ctx context.Context, mfaToken string, proof MFAProof) (*TokenPair, error)
+	// BeginPasskeyLogin starts a passkey assertion. Without an mfaToken it is
+	// a passwordless login; with one it answers that pending MFA challenge.
+	BeginPasskeyLogin(ctx context.Context, mfaToken string) (*PasskeyCeremony, error)
+	FinishPasskeyLogin(ctx context.Context, sessionToken string, response []byte, client clientinfo.Info) (*TokenPair, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(ctx context.Context, claims *token.Claims) error
+	LogoutAllSessions(ctx context.Context, userID model.ID) error
 	ForgotPassword(ctx context.Context, email string) error
-	ResetPassword(ctx context.Context, token, new_password string) error
+	ResetPassword(ctx context.Context, resetToken, newPassword string) error
+	// ChangePassword replaces the caller's password after checking the current
+	// one, which counts towards the same lockout as Login. With
+	// revokeOtherSessions every session but the caller's is signed out.
+	ChangePassword(ctx context.Context, claims *token.Claims, currentPassword, newPassword string, revokeOtherSessions bool, client clientinfo.Info) error
+	VerifyEmail(ctx context.Context, token string) error
+	// RequestEmailChange takes the current password like ChangePassword.
+	RequestEmailChange(ctx context.Context, userID model.ID, newEmail, currentPassword string, client clientinfo.Info) error
+	ConfirmEmailChange(ctx context.Context, changeToken string) error
+	UndoEmailChange(ctx context.Context, undoToken string) error
+	// ResendVerificationEmail sends a new verification link. It succeeds
+	// silently for unknown or already verified addresses.
+	ResendVerificationEmail(ctx context.Context, email string) error
+	// RequestMagicLink mails a single-use login link. Unknown addresses are
+	// ignored so the caller cannot tell whether an account exists.
+	RequestMagicLink(ctx context.Context, email string) error
+	ConsumeMagicLink(ctx context.Context, token string, client clientinfo.Info) (*LoginResult, error)
+	// RequestLoginCode mails a numeric one-time code for a passwordless login.
+	// Unknown addresses are ignored like in RequestMagicLink.
+	RequestLoginCode(ctx context.Context, email string) error
+	VerifyLoginCode(ctx context.Context, email, code string, client clientinfo.Info) (*LoginResult, error)
+	RequestMFAEmailCode(ctx context.Context, mfaToken string) error
+	RequestPhoneVerification(ctx context.Context, userID model.ID) error
+	VerifyPhone(ctx context.Context, userID model.ID, code string) error
+	// RequestMFASMSCode texts a code that VerifyMFA accepts as the second
+	// factor. Only verified phone numbers qualify.
+	RequestMFASMSCode(ctx context.Context, mfaToken string) error
+	// UpdateUsername sets the user's username, or removes it when empty.
+	UpdateUsername(ctx context.Context, userID model.ID, username string) (*model.User, error)
+	// UpdatePhoneNumber sets the user's phone number, or removes it when
+	// empty, after checking the current password like ChangePassword.
+	UpdatePhoneNumber(ctx context.Context, userID model.ID, phoneNumber, currentPassword string, client clientinfo.Info) (*model.User, error)
+	JWKS() token.JWKSet
 }
 
+// Identifiers are the optional login identifiers besides the email address.
+type Identifiers struct {
+	Username    string
+	PhoneNumber string
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// LoginResult carries either the issued tokens or, when the user has a second
+// factor enabled, the challenge token to pass to VerifyMFA.
+type LoginResult struct {
+	Tokens   *TokenPair
+	MFAToken string
+}
+
+// EmailVerificationPolicy decides how logins of users who have not verified
+// their email address are handled.
+type EmailVerificationPolicy string
+
+const (
+	// EmailVerificationClaim lets the user in with email_verified=false in the
+	// access token, leaving the decision to the services that consume it.
+	EmailVerificationClaim EmailVerificationPolicy = "claim"
+	// EmailVerificationRequired refuses the login until the address is verified.
+	EmailVerificationRequired EmailVerificationPolicy = "required"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+const passwordResetTTL = 15 * time.Minute
+
+const (
+	mfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+)
+
 type authService struct {
-	repo         repository.UserRepository
-	redis        *redis.Client
-	emailService EmailService
+	repo          repository.UserRepository
+	refreshTokens repository.RefreshTokenRepository
+	revocations   repository.TokenRevocationRepository
+	sessions      SessionService
+	mfa           MFAService
+	passkeys      PasskeyService
+	lockout       LockoutService
+	keys          token.KeySet
+	redis         *redis.Client
+	emailService  EmailService
+	sms           SMSService
+	verification  EmailVerificationPolicy
+	passwords     validation.PasswordPolicy
+	history       repository.PasswordHistoryRepository
+	emails        validation.EmailNormalizer
+	// revokeOnEmailChange signs the user out everywhere once a new address is
+	// confirmed.
+	revokeOnEmailChange bool
 }
 
-func NewAuthService(repo repository.UserRepository, redis *redis.Client, emailService EmailService) AuthService {
-	return &authService{repo: repo, redis: redis, emailService: emailService}
+func NewAuthService(repo repository.UserRepository, refreshTokens repository.RefreshTokenRepository, revocations repository.TokenRevocationRepository, sessions SessionService, mfa MFAService, passkeys PasskeyService, lockout LockoutService, keys token.KeySet, redis *redis.Client, emailService EmailService, sms SMSService, verification EmailVerificationPolicy, passwords validation.PasswordPolicy, history repository.PasswordHistoryRepository, emails validation.EmailNormalizer, revokeOnEmailChange bool) AuthService {
+	return &authService{repo: repo, refreshTokens: refreshTokens, revocations: revocations, sessions: sessions, mfa: mfa, passkeys: passkeys, lockout: lockout, keys: keys, redis: redis, emailService: emailService, sms: sms, verification: verification, passwords: passwords, history: history, emails: emails, revokeOnEmailChange: revokeOnEmailChange}
 }
 
-func (s *authService) Register(ctx context.Context, email, password string) (*model.User, error) {
-	const minEntropyBits = 60
-	err := passwordValidator.Validate(password, minEntropyBits)
+func (s *authService) Register(ctx context.Context, email, password string, identifiers Identifiers) (*model.User, error) {
+	email, err := s.emails.Normalize(email)
 	if err != nil {
-		return nil, fmt.Errorf("password too weak: %v", err)
+		return nil, err
+	}
+
+	var username, phoneNumber *string
+
+	if identifiers.Username != "" {
+		if err := validation.ValidateUsername(identifiers.Username); err != nil {
+			return nil, err
+		}
+		username = &identifiers.Username
+	}
+
+	if identifiers.PhoneNumber != "" {
+		normalized, err := validation.NormalizePhoneNumber(identifiers.PhoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		phoneNumber = &normalized
+	}
+
+	if err := s.passwords.Check(password, email); err != nil {
+		return nil, err
 	}
 
 	hashedPassword, err := hash.HashPassword(password)
@@ -49,6 +164,8 @@ func (s *authService) Register(ctx context.Context, email, password string) (*mo
 	user := &model.User{
 		ID:           model.NewID(),
 		Email:        email,
+		Username:     username,
+		PhoneNumber:  phoneNumber,
 		PasswordHash: hashedPassword,
 		CreatedAt:    model.NewTimestamp(),
 	}
@@ -59,43 +176,547 @@ func (s *authService) Register(ctx context.Context, email, password string) (*mo
 		return nil, fmt.Errorf("could not create user: %w", err)
 	}
 
+	s.recordPasswordHistory(ctx, user.ID, hashedPassword)
+
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		// The user can ask for another link with ResendVerificationEmail.
+		log.Printf("WARN: authService.Register: %v", err)
+	}
+
 	return user, nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (string, error) {
-	user, err := s.repo.GetByEmail(ctx, email)
+func (s *authService) Login(ctx context.Context, identifier, password string, client clientinfo.Info) (*LoginResult, error) {
+	identifier = s.normalizeIdentifier(identifier)
 
-	if err != nil {
-		return "", err
+	user, err := s.repo.GetByIdentifier(ctx, identifier)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("authService.Login (repo): %w", err)
+	}
+
+	// Failures are counted against the account's email, so switching between
+	// its identifiers does not buy extra guesses.
+	email := identifier
+	if user != nil {
+		email = user.Email
+	}
+
+	if err := s.lockout.Check(ctx, email, client.IP); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, s.loginFailed(ctx, email, client)
 	}
 
 	if !hash.CheckPasswordHash(password, user.PasswordHash) {
-		return "", errors.New("invalid credentials")
+		return nil, s.loginFailed(ctx, email, client)
+	}
+
+	if err := s.lockout.RecordSuccess(ctx, email); err != nil {
+		log.Printf("WARN: authService.Login: %v", err)
+	}
+
+	s.rehashPassword(ctx, user, password)
+
+	// Checked before the MFA challenge so the user is not asked for a second
+	// factor only to be refused afterwards.
+	if err := s.checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user.ID, firstFactorPassword, client)
+}
+
+// rehashPassword upgrades a stored hash that uses an old scheme or outdated
+// parameters, now that the plaintext is at hand. Failures only delay the
+// upgrade to the next login.
+func (s *authService) rehashPassword(ctx context.Context, user *model.User, password string) {
+	if !hash.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	newHash, err := hash.HashPassword(password)
+	if err != nil {
+		log.Printf("WARN: authService.rehashPassword (hash): %v", err)
+		return
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, newHash); err != nil {
+		log.Printf("WARN: authService.rehashPassword (repo): %v", err)
+		return
+	}
+
+	user.PasswordHash = newHash
+}
+
+// setPassword replaces the user's password after checking it against the
+// policy and the user's recent passwords.
+func (s *authService) setPassword(ctx context.Context, user *model.User, newPassword string) error {
+	if err := s.passwords.Check(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	if err := s.checkPasswordHistory(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := hash.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("authService.setPassword (hash): %w", err)
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("authService.setPassword (repo): %w", err)
+	}
+
+	user.PasswordHash = hashedPassword
+	s.recordPasswordHistory(ctx, user.ID, hashedPassword)
+
+	return nil
+}
+
+// checkPasswordHistory compares password with the current hash, which covers
+// users from before the history was kept, and with the recorded ones.
+func (s *authService) checkPasswordHistory(ctx context.Context, user *model.User, password string) error {
+	if s.passwords.History <= 0 {
+		return nil
+	}
+
+	if hash.CheckPasswordHash(password, user.PasswordHash) {
+		return s.passwords.ReusedError()
+	}
+
+	hashes, err := s.history.ListRecent(ctx, user.ID, s.passwords.History)
+	if err != nil {
+		return fmt.Errorf("authService.checkPasswordHistory (repo): %w", err)
+	}
+
+	for _, h := range hashes {
+		if hash.CheckPasswordHash(password, h) {
+			return s.passwords.ReusedError()
+		}
+	}
+
+	return nil
+}
+
+// recordPasswordHistory keeps the newest History hashes of the user. A
+// failure only leaves that password out of the reuse check.
+func (s *authService) recordPasswordHistory(ctx context.Context, userID model.ID, passwordHash string) {
+	if s.passwords.History <= 0 {
+		return
+	}
+
+	entry := &model.PasswordHistoryEntry{
+		ID:           model.NewID(),
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		CreatedAt:    model.NewTimestamp(),
+	}
+
+	if err := s.history.Add(ctx, entry, s.passwords.History); err != nil {
+		log.Printf("WARN: authService.recordPasswordHistory: %v", err)
+	}
+}
+
+// normalizeEmail prepares an address for a lookup. Addresses that cannot be
+// normalized are only trimmed, so they simply match no account.
+func (s *authService) normalizeEmail(email string) string {
+	normalized, err := s.emails.Normalize(email)
+	if err != nil {
+		return strings.TrimSpace(email)
+	}
+	return normalized
+}
+
+// normalizeIdentifier brings a login identifier to its stored form. Usernames
+// are stored as given and compared case-insensitively.
+func (s *authService) normalizeIdentifier(identifier string) string {
+	identifier = strings.TrimSpace(identifier)
+
+	switch {
+	case strings.Contains(identifier, "@"):
+		return s.normalizeEmail(identifier)
+	case strings.HasPrefix(identifier, "+"):
+		if phone, err := validation.NormalizePhoneNumber(identifier); err == nil {
+			return phone
+		}
 	}
 
-	secret := os.Getenv("JWT_SECRET")
-	token, err := token.GenerateToken(user.ID, secret)
+	return identifier
+}
 
+// loginFailed counts a wrong email or password. Unknown emails are counted
+// like known ones so lockouts do not reveal which accounts exist.
+func (s *authService) loginFailed(ctx context.Context, email string, client clientinfo.Info) error {
+	if err := s.lockout.RecordFailure(ctx, email, client.IP); err != nil {
+		if errors.Is(err, ErrLoginLocked) {
+			return err
+		}
+		log.Printf("WARN: authService.loginFailed: %v", err)
+	}
+
+	return ErrInvalidCredentials
+}
+
+// First factors recorded in an MFA challenge. A login that started from the
+// inbox cannot use an email code as its second factor.
+const (
+	firstFactorPassword = "password"
+	firstFactorEmail    = "email"
+)
+
+// completeLogin finishes a successful first-factor login: it either opens a
+// session right away or, if the user has MFA enabled, parks the login behind
+// an MFA challenge.
+func (s *authService) completeLogin(ctx context.Context, userID model.ID, firstFactor string, client clientinfo.Info) (*LoginResult, error) {
+	mfaEnabled, err := s.mfa.IsEnabled(ctx, userID)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("authService.completeLogin (mfa): %w", err)
 	}
 
-	return token, nil
+	if mfaEnabled {
+		mfaToken, err := s.createMFAChallenge(ctx, userID, firstFactor, client)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := s.startSession(ctx, userID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+func (s *authService) startSession(ctx context.Context, userID model.ID, client clientinfo.Info) (*TokenPair, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("authService.startSession (user): %w", err)
+	}
+
+	if err := s.checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessions.Create(ctx, userID, client)
+	if err != nil {
+		return nil, fmt.Errorf("authService.startSession (session): %w", err)
+	}
+
+	return s.issueTokens(ctx, user, session.ID)
+}
+
+func (s *authService) checkEmailVerified(user *model.User) error {
+	if s.verification == EmailVerificationRequired && user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+func mfaChallengeKey(mfaToken string) string {
+	return fmt.Sprintf("mfa_challenge:%s", token.HashOpaqueToken(mfaToken))
+}
+
+func (s *authService) createMFAChallenge(ctx context.Context, userID model.ID, firstFactor string, client clientinfo.Info) (string, error) {
+	mfaToken, err := token.GenerateOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("authService.createMFAChallenge (token): %w", err)
+	}
+
+	key := mfaChallengeKey(mfaToken)
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, map[string]any{
+		"user_id":      userID.String(),
+		"first_factor": firstFactor,
+		"device":       client.Device,
+		"user_agent":   client.UserAgent,
+		"ip":           client.IP,
+		"attempts":     0,
+	})
+	pipe.Expire(ctx, key, mfaChallengeTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("authService.createMFAChallenge (redis): %w", err)
+	}
+
+	return mfaToken, nil
+}
+
+func (s *authService) VerifyMFA(ctx context.Context, mfaToken string, proof MFAProof) (*TokenPair, error) {
+	challenge, err := s.getMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if proof.EmailCode != "" {
+		if challenge.firstFactor == firstFactorEmail {
+			return nil, ErrMFAFactorNotAllowed
+		}
+		err = s.checkEmailCode(ctx, challenge.userID, emailCodeMFA, proof.EmailCode)
+	} else if proof.SMSCode != "" {
+		err = s.checkCode(ctx, smsCodeKey(smsCodeMFA, challenge.userID), proof.SMSCode, ErrInvalidSMSCode)
+	} else {
+		err = s.mfa.Verify(ctx, challenge.userID, proof)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) || errors.Is(err, ErrInvalidEmailCode) || errors.Is(err, ErrInvalidSMSCode) {
+			s.countMFAFailure(ctx, challenge.key)
+		}
+		return nil, err
+	}
+
+	if err := s.consumeMFAChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, challenge.userID, challenge.client)
+}
+
+func (s *authService) BeginPasskeyLogin(ctx context.Context, mfaToken string) (*PasskeyCeremony, error) {
+	if mfaToken == "" {
+		return s.passkeys.BeginLogin(ctx, nil, "")
+	}
+
+	challenge, err := s.getMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.passkeys.BeginLogin(ctx, &challenge.userID, mfaToken)
+}
+
+func (s *authService) FinishPasskeyLogin(ctx context.Context, sessionToken string, response []byte, client clientinfo.Info) (*TokenPair, error) {
+	userID, mfaToken, err := s.passkeys.FinishLogin(ctx, sessionToken, response)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfaToken == "" {
+		// A discoverable passkey with user verification is a complete login.
+		return s.startSession(ctx, userID, client)
+	}
+
+	challenge, err := s.getMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge.userID != userID {
+		return nil, ErrInvalidPasskey
+	}
+
+	if err := s.consumeMFAChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, challenge.userID, challenge.client)
+}
+
+// mfaChallenge is a login that passed the first factor and waits for the
+// second one.
+type mfaChallenge struct {
+	key         string
+	userID      model.ID
+	firstFactor string
+	client      clientinfo.Info
+}
+
+func (s *authService) getMFAChallenge(ctx context.Context, mfaToken string) (*mfaChallenge, error) {
+	key := mfaChallengeKey(mfaToken)
+
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("authService.getMFAChallenge (redis get): %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrInvalidMFAToken
+	}
+
+	userID, err := model.ParseID(fields["user_id"])
+	if err != nil {
+		return nil, fmt.Errorf("authService.getMFAChallenge (parseID): %w", err)
+	}
+
+	return &mfaChallenge{
+		key:         key,
+		userID:      userID,
+		firstFactor: fields["first_factor"],
+		client: clientinfo.Info{
+			Device:    fields["device"],
+			UserAgent: fields["user_agent"],
+			IP:        fields["ip"],
+		},
+	}, nil
+}
+
+func (s *authService) consumeMFAChallenge(ctx context.Context, challenge *mfaChallenge) error {
+	deleted, err := s.redis.Del(ctx, challenge.key).Result()
+	if err != nil {
+		return fmt.Errorf("authService.consumeMFAChallenge (redis del): %w", err)
+	}
+	if deleted == 0 {
+		// A concurrent request already completed this challenge.
+		return ErrInvalidMFAToken
+	}
+
+	return nil
+}
+
+// countMFAFailure burns the challenge after too many wrong codes, forcing the
+// caller to authenticate with the first factor again.
+func (s *authService) countMFAFailure(ctx context.Context, key string) {
+	pipe := s.redis.TxPipeline()
+	incr := pipe.HIncrBy(ctx, key, "attempts", 1)
+	// Guards against recreating a challenge that expired in the meantime
+	// without a TTL.
+	pipe.ExpireNX(ctx, key, mfaChallengeTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("WARN: authService.countMFAFailure (redis incr): %v", err)
+		return
+	}
+
+	if incr.Val() >= mfaChallengeMaxAttempts {
+		if err := s.redis.Del(ctx, key).Err(); err != nil {
+			log.Printf("WARN: authService.countMFAFailure (redis del): %v", err)
+		}
+	}
+}
+
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	current, err := s.refreshTokens.GetByHash(ctx, token.HashOpaqueToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("authService.RefreshToken (get): %w", err)
+	}
+
+	active, err := s.refreshTokens.IsFamilyActive(ctx, current.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("authService.RefreshToken (family): %w", err)
+	}
+	if !active {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rotated, err := s.refreshTokens.MarkRotated(ctx, current.Hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("authService.RefreshToken (rotate): %w", err)
+	}
+
+	if !rotated {
+		if err := s.refreshTokens.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return nil, fmt.Errorf("authService.RefreshToken (revoke family): %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if err := s.sessions.Touch(ctx, current.FamilyID); err != nil {
+		log.Printf("WARN: authService.RefreshToken: %v", err)
+	}
+
+	user, err := s.repo.GetByID(ctx, current.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("authService.RefreshToken (user): %w", err)
+	}
+
+	return s.issueTokens(ctx, user, current.FamilyID)
+}
+
+func (s *authService) Logout(ctx context.Context, claims *token.Claims) error {
+	if err := s.revocations.RevokeToken(ctx, claims.TokenID, claims.ExpiresAt); err != nil {
+		return fmt.Errorf("authService.Logout (revoke access): %w", err)
+	}
+
+	if claims.SessionID == (model.ID{}) {
+		return nil
+	}
+
+	err := s.sessions.Revoke(ctx, claims.UserID, claims.SessionID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("authService.Logout (session): %w", err)
+	}
+
+	return nil
+}
+
+func (s *authService) LogoutAllSessions(ctx context.Context, userID model.ID) error {
+	if err := s.revokeAllTokens(ctx, userID); err != nil {
+		return fmt.Errorf("authService.LogoutAllSessions: %w", err)
+	}
+
+	return nil
+}
+
+func (s *authService) JWKS() token.JWKSet {
+	return token.PublicJWKS(s.keys)
+}
+
+// issueTokens signs an access token for the session and starts or continues
+// its refresh token family, which shares the session id.
+func (s *authService) issueTokens(ctx context.Context, user *model.User, sessionID model.ID) (*TokenPair, error) {
+	accessToken, err := token.GenerateToken(user.ID, sessionID, user.EmailVerifiedAt != nil, user.TokenVersion, s.keys)
+	if err != nil {
+		return nil, fmt.Errorf("authService.issueTokens (access): %w", err)
+	}
+
+	refreshToken, err := token.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("authService.issueTokens (refresh): %w", err)
+	}
+
+	now := model.NewTimestamp()
+	err = s.refreshTokens.Create(ctx, &model.RefreshToken{
+		Hash:      token.HashOpaqueToken(refreshToken),
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(token.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("authService.issueTokens (store): %w", err)
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// passwordResetKey maps a reset token to its user. passwordResetUserKey holds
+// the hash of the user's newest token, so a token is only accepted while it is
+// the latest one issued to that user.
+func passwordResetKey(resetToken string) string {
+	return fmt.Sprintf("password_reset:%s", token.HashOpaqueToken(resetToken))
+}
+
+func passwordResetUserKey(userID model.ID) string {
+	return fmt.Sprintf("password_reset_user:%s", userID)
 }
 
 func (s *authService) ForgotPassword(ctx context.Context, email string) error {
-	user, err := s.repo.GetByEmail(ctx, email)
+	user, err := s.repo.GetByEmail(ctx, s.normalizeEmail(email))
 
 	if err != nil {
 		return err
 	}
 
-	resetToken := uuid.New().String()
+	resetToken, err := token.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("authService.ForgotPassword (token): %w", err)
+	}
 
-	key := fmt.Sprintf("password_reset:%s", resetToken)
-	err = s.redis.Set(ctx, key, user.ID.String(), 15*time.Minute).Err()
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, passwordResetKey(resetToken), user.ID.String(), passwordResetTTL)
+	pipe.Set(ctx, passwordResetUserKey(user.ID), token.HashOpaqueToken(resetToken), passwordResetTTL)
 
-	if err != nil {
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("authService.ForgotPassword (redis set): %w", err)
 	}
 
@@ -109,8 +730,11 @@ func (s *authService) ForgotPassword(ctx context.Context, email string) error {
 	return nil
 }
 
-func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
-	key := fmt.Sprintf("password_reset:%s", token)
+// ResetPassword accepts only the user's newest reset token. On success it
+// invalidates every outstanding reset token, session and access token of the
+// user.
+func (s *authService) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	key := passwordResetKey(resetToken)
 
 	userIDStr, err := s.redis.Get(ctx, key).Result()
 	if err != nil {
@@ -125,20 +749,184 @@ func (s *authService) ResetPassword(ctx context.Context, token, newPassword stri
 		return fmt.Errorf("authService.ResetPassword (parseID): %w", err)
 	}
 
-	hashedPassword, err := hash.HashPassword(newPassword)
-	if err != nil {
-		return fmt.Errorf("authService.ResetPassword (hash): %w", err)
+	latest, err := s.redis.Get(ctx, passwordResetUserKey(userID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("authService.ResetPassword (redis latest): %w", err)
+	}
+	if latest != token.HashOpaqueToken(resetToken) {
+		return repository.ErrNotFound
 	}
 
-	err = s.repo.UpdatePassword(ctx, userID, hashedPassword)
+	user, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("authService.ResetPassword (repo): %w", err)
+		return fmt.Errorf("authService.ResetPassword (user): %w", err)
 	}
 
-	err = s.redis.Del(ctx, key).Err()
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	err = s.redis.Del(ctx, key, passwordResetUserKey(userID)).Err()
 	if err != nil {
 		log.Printf("WARN: failed to delete reset token from redis: %v", err)
 	}
 
+	if err := s.revokeAllTokens(ctx, userID); err != nil {
+		return fmt.Errorf("authService.ResetPassword: %w", err)
+	}
+
+	return nil
+}
+
+// revokeAllTokens signs the user out everywhere: raising the token version
+// rejects every access token issued so far, and revoking the sessions ends
+// their refresh tokens.
+func (s *authService) revokeAllTokens(ctx context.Context, userID model.ID) error {
+	if err := s.repo.IncrementTokenVersion(ctx, userID); err != nil {
+		return fmt.Errorf("authService.revokeAllTokens (token version): %w", err)
+	}
+
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("authService.revokeAllTokens (sessions): %w", err)
+	}
+
+	return nil
+}
+
+// checkCurrentPassword confirms a sensitive change made with an access
+// token. Failures count towards the login lockout, so a stolen access token
+// does not become an unlimited password oracle.
+func (s *authService) checkCurrentPassword(ctx context.Context, user *model.User, password string, client clientinfo.Info) error {
+	if err := s.lockout.Check(ctx, user.Email, client.IP); err != nil {
+		return err
+	}
+
+	if !hash.CheckPasswordHash(password, user.PasswordHash) {
+		return s.loginFailed(ctx, user.Email, client)
+	}
+
+	if err := s.lockout.RecordSuccess(ctx, user.Email); err != nil {
+		log.Printf("WARN: authService.checkCurrentPassword: %v", err)
+	}
+
+	return nil
+}
+
+func (s *authService) ChangePassword(ctx context.Context, claims *token.Claims, currentPassword, newPassword string, revokeOtherSessions bool, client clientinfo.Info) error {
+	user, err := s.repo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("authService.ChangePassword (user): %w", err)
+	}
+
+	if err := s.checkCurrentPassword(ctx, user, currentPassword, client); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	if revokeOtherSessions {
+		if err := s.sessions.RevokeOthers(ctx, user.ID, claims.SessionID); err != nil {
+			return fmt.Errorf("authService.ChangePassword (sessions): %w", err)
+		}
+	}
+
+	go func() {
+		if err := s.emailService.SendPasswordChanged(user.Email); err != nil {
+			log.Printf("ERROR: authService.ChangePassword background email: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func emailVerificationKey(verificationToken string) string {
+	return fmt.Sprintf("email_verification:%s", token.HashOpaqueToken(verificationToken))
+}
+
+// sendVerificationEmail mails a link bound to the user's current address, so
+// that a link sent before an email change cannot verify the new one.
+func (s *authService) sendVerificationEmail(ctx context.Context, user *model.User) error {
+	verificationToken, err := token.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("authService.sendVerificationEmail (token): %w", err)
+	}
+
+	key := emailVerificationKey(verificationToken)
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, map[string]any{
+		"user_id": user.ID.String(),
+		"email":   user.Email,
+	})
+	pipe.Expire(ctx, key, emailVerificationTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("authService.sendVerificationEmail (redis): %w", err)
+	}
+
+	go func() {
+		err := s.emailService.SendVerificationLink(user.Email, verificationToken)
+		if err != nil {
+			log.Printf("ERROR: authService.sendVerificationEmail background email: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *authService) VerifyEmail(ctx context.Context, verificationToken string) error {
+	key := emailVerificationKey(verificationToken)
+
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("authService.VerifyEmail (redis get): %w", err)
+	}
+	if len(fields) == 0 {
+		return ErrInvalidVerificationToken
+	}
+
+	userID, err := model.ParseID(fields["user_id"])
+	if err != nil {
+		return fmt.Errorf("authService.VerifyEmail (parseID): %w", err)
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return fmt.Errorf("authService.VerifyEmail (user): %w", err)
+	}
+
+	if user.Email != fields["email"] {
+		return ErrInvalidVerificationToken
+	}
+
+	if err := s.repo.MarkEmailVerified(ctx, userID, model.NewTimestamp()); err != nil {
+		return fmt.Errorf("authService.VerifyEmail (repo): %w", err)
+	}
+
+	if err := s.redis.Del(ctx, key).Err(); err != nil {
+		log.Printf("WARN: failed to delete verification token from redis: %v", err)
+	}
+
 	return nil
 }
+
+func (s *authService) ResendVerificationEmail(ctx context.Context, email string) error {
+	user, err := s.repo.GetByEmail(ctx, s.normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("authService.ResendVerificationEmail (user): %w", err)
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
@@ -1,7 +1,40 @@
 package service
 
+import (
+	"strings"
+	"text/template"
+	"time"
+)
+
 type EmailService interface {
 	SendResetLink(email, token string) error
+	SendVerificationLink(email, token string) error
+	SendMagicLink(email, token string) error
+	SendLoginCode(email, code string, expiresIn time.Duration) error
+	// SendPasswordChanged tells the user their password was changed, so an
+	// unexpected change can be noticed.
+	SendPasswordChanged(email string) error
+	SendEmailChangeConfirmation(newEmail, token string) error
+	// SendEmailChangeNotice warns the current address about a requested change
+	// and carries the token that undoes it.
+	SendEmailChangeNotice(oldEmail, newEmail, undoToken string) error
+}
+
+var loginCodeTemplate = template.Must(template.New("login_code").Parse(
+	`Your login code is {{.Code}}.
+
+It expires in {{.Minutes}} minutes. If you did not try to sign in, you can ignore this email.
+`))
+
+type loginCodeData struct {
+	Code    string
+	Minutes int
+}
+
+func renderLoginCode(code string, expiresIn time.Duration) (string, error) {
+	var sb strings.Builder
+	err := loginCodeTemplate.Execute(&sb, loginCodeData{Code: code, Minutes: int(expiresIn.Minutes())})
+	return sb.String(), err
 }
 
 type consoleEmailService struct{}
@@ -12,6 +45,47 @@ func (s *consoleEmailService) SendResetLink(email, token string) error {
 	return nil
 }
 
+func (s *consoleEmailService) SendVerificationLink(email, token string) error {
+	println("Verification token: " + token)
+
+	return nil
+}
+
+func (s *consoleEmailService) SendMagicLink(email, token string) error {
+	println("Magic link token: " + token)
+
+	return nil
+}
+
+func (s *consoleEmailService) SendLoginCode(email, code string, expiresIn time.Duration) error {
+	body, err := renderLoginCode(code, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	println(body)
+
+	return nil
+}
+
+func (s *consoleEmailService) SendPasswordChanged(email string) error {
+	println("Password changed for " + email)
+
+	return nil
+}
+
+func (s *consoleEmailService) SendEmailChangeConfirmation(newEmail, token string) error {
+	println("Email change token: " + token)
+
+	return nil
+}
+
+func (s *consoleEmailService) SendEmailChangeNotice(oldEmail, newEmail, undoToken string) error {
+	println("Email change to " + newEmail + " requested, undo token: " + undoToken)
+
+	return nil
+}
+
 func NewEmailService() EmailService {
 	return &consoleEmailService{}
 }
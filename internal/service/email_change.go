@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	emailChangeTTL = 24 * time.Hour
+	// emailChangeUndoTTL gives the owner of the previous address time to
+	// notice the change after it was confirmed.
+	emailChangeUndoTTL = 7 * 24 * time.Hour
+)
+
+func emailChangeKey(changeToken string) string {
+	return fmt.Sprintf("email_change:%s", token.HashOpaqueToken(changeToken))
+}
+
+func emailChangeUndoKey(undoToken string) string {
+	return fmt.Sprintf("email_change_undo:%s", token.HashOpaqueToken(undoToken))
+}
+
+// emailChangeUserKey holds the hash of the user's newest change token, so a
+// new request supersedes earlier ones and an undo cancels the pending one.
+func emailChangeUserKey(userID model.ID) string {
+	return fmt.Sprintf("email_change_user:%s", userID)
+}
+
+// RequestEmailChange mails a confirmation link to newEmail and a notice with
+// an undo link to the current address. The address only changes once the
+// link is confirmed. Whether newEmail is taken is only checked then, so the
+// request does not tell the caller whether another account uses it.
+func (s *authService) RequestEmailChange(ctx context.Context, userID model.ID, newEmail, currentPassword string, client clientinfo.Info) error {
+	newEmail, err := s.emails.Normalize(newEmail)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("authService.RequestEmailChange (user): %w", err)
+	}
+
+	if err := s.checkCurrentPassword(ctx, user, currentPassword, client); err != nil {
+		return err
+	}
+
+	if strings.EqualFold(user.Email, newEmail) {
+		return ErrEmailUnchanged
+	}
+
+	changeToken, err := token.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("authService.RequestEmailChange (token): %w", err)
+	}
+
+	undoToken, err := token.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("authService.RequestEmailChange (undo token): %w", err)
+	}
+
+	fields := map[string]any{
+		"user_id":   user.ID.String(),
+		"old_email": user.Email,
+		"new_email": newEmail,
+	}
+
+	changeKey, undoKey := emailChangeKey(changeToken), emailChangeUndoKey(undoToken)
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, changeKey, fields)
+	pipe.Expire(ctx, changeKey, emailChangeTTL)
+	pipe.Set(ctx, emailChangeUserKey(user.ID), token.HashOpaqueToken(changeToken), emailChangeTTL)
+	pipe.HSet(ctx, undoKey, fields)
+	pipe.Expire(ctx, undoKey, emailChangeUndoTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("authService.RequestEmailChange (redis): %w", err)
+	}
+
+	go func() {
+		if err := s.emailService.SendEmailChangeConfirmation(newEmail, changeToken); err != nil {
+			log.Printf("ERROR: authService.RequestEmailChange background email: %v", err)
+		}
+		if err := s.emailService.SendEmailChangeNotice(user.Email, newEmail, undoToken); err != nil {
+			log.Printf("ERROR: authService.RequestEmailChange background notice: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// ConfirmEmailChange applies the change behind changeToken. Opening the link
+// proves control of the new address, so it is stored as verified.
+func (s *authService) ConfirmEmailChange(ctx context.Context, changeToken string) error {
+	key := emailChangeKey(changeToken)
+
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("authService.ConfirmEmailChange (redis get): %w", err)
+	}
+	if len(fields) == 0 {
+		return ErrInvalidEmailChangeToken
+	}
+
+	userID, err := model.ParseID(fields["user_id"])
+	if err != nil {
+		return fmt.Errorf("authService.ConfirmEmailChange (parseID): %w", err)
+	}
+
+	latest, err := s.redis.Get(ctx, emailChangeUserKey(userID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("authService.ConfirmEmailChange (redis latest): %w", err)
+	}
+	if latest != token.HashOpaqueToken(changeToken) {
+		return ErrInvalidEmailChangeToken
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidEmailChangeToken
+		}
+		return fmt.Errorf("authService.ConfirmEmailChange (user): %w", err)
+	}
+
+	if user.Email != fields["old_email"] {
+		return ErrInvalidEmailChangeToken
+	}
+
+	// Another account may have taken the address since the request.
+	if err := s.repo.UpdateEmail(ctx, userID, fields["new_email"], model.NewTimestamp()); err != nil {
+		return fmt.Errorf("authService.ConfirmEmailChange (repo): %w", err)
+	}
+
+	if err := s.redis.Del(ctx, key, emailChangeUserKey(userID)).Err(); err != nil {
+		log.Printf("WARN: failed to delete email change token from redis: %v", err)
+	}
+
+	if s.revokeOnEmailChange {
+		if err := s.revokeAllTokens(ctx, userID); err != nil {
+			return fmt.Errorf("authService.ConfirmEmailChange: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UndoEmailChange cancels a pending change or, once confirmed, restores the
+// previous address. An unexpected change suggests the account was taken over,
+// so every session is revoked either way.
+func (s *authService) UndoEmailChange(ctx context.Context, undoToken string) error {
+	key := emailChangeUndoKey(undoToken)
+
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("authService.UndoEmailChange (redis get): %w", err)
+	}
+	if len(fields) == 0 {
+		return ErrInvalidEmailChangeToken
+	}
+
+	userID, err := model.ParseID(fields["user_id"])
+	if err != nil {
+		return fmt.Errorf("authService.UndoEmailChange (parseID): %w", err)
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidEmailChangeToken
+		}
+		return fmt.Errorf("authService.UndoEmailChange (user): %w", err)
+	}
+
+	switch user.Email {
+	case fields["old_email"]:
+		if err := s.redis.Del(ctx, emailChangeUserKey(userID)).Err(); err != nil {
+			return fmt.Errorf("authService.UndoEmailChange (redis cancel): %w", err)
+		}
+	case fields["new_email"]:
+		if err := s.repo.UpdateEmail(ctx, userID, fields["old_email"], model.NewTimestamp()); err != nil {
+			return fmt.Errorf("authService.UndoEmailChange (repo): %w", err)
+		}
+	default:
+		return ErrInvalidEmailChangeToken
+	}
+
+	if err := s.redis.Del(ctx, key).Err(); err != nil {
+		log.Printf("WARN: failed to delete email change undo token from redis: %v", err)
+	}
+
+	if err := s.revokeAllTokens(ctx, userID); err != nil {
+		return fmt.Errorf("authService.UndoEmailChange: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package service
+
+import "errors"
+
+var (
+	ErrInvalidCredentials       = errors.New("invalid credentials")
+	ErrInvalidRefreshToken      = errors.New("invalid refresh token")
+	ErrRefreshTokenReused       = errors.New("refresh token reused")
+	ErrInvalidMFAToken          = errors.New("invalid mfa token")
+	ErrInvalidMFACode           = errors.New("invalid mfa code")
+	ErrMFANotEnrolled           = errors.New("mfa not enrolled")
+	ErrMFAAlreadyEnabled        = errors.New("mfa already enabled")
+	ErrInvalidPasskey           = errors.New("invalid passkey")
+	ErrInvalidPasskeySession    = errors.New("invalid passkey session")
+	ErrEmailNotVerified         = errors.New("email not verified")
+	ErrInvalidVerificationToken = errors.New("invalid verification token")
+	ErrInvalidMagicLink         = errors.New("invalid magic link")
+	ErrInvalidEmailCode         = errors.New("invalid email code")
+	ErrLoginLocked              = errors.New("login locked")
+	ErrMFAFactorNotAllowed      = errors.New("mfa factor not allowed")
+	ErrEmailUnchanged           = errors.New("email unchanged")
+	ErrInvalidEmailChangeToken  = errors.New("invalid email change token")
+	ErrNoPhoneNumber            = errors.New("no phone number")
+	ErrPhoneNotVerified         = errors.New("phone number not verified")
+	ErrInvalidSMSCode           = errors.New("invalid sms code")
+	ErrKeyRotationPending       = errors.New("key rotation pending")
+)
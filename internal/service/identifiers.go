@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/validation"
+)
+
+// UpdateUsername applies the same format and uniqueness rules as Register.
+func (s *authService) UpdateUsername(ctx context.Context, userID model.ID, username string) (*model.User, error) {
+	var value *string
+	if username != "" {
+		if err := validation.ValidateUsername(username); err != nil {
+			return nil, err
+		}
+		value = &username
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("authService.UpdateUsername (user): %w", err)
+	}
+
+	if err := s.repo.UpdateUsername(ctx, userID, value); err != nil {
+		return nil, fmt.Errorf("authService.UpdateUsername (repo): %w", err)
+	}
+
+	user.Username = value
+
+	return user, nil
+}
+
+// UpdatePhoneNumber applies the same format and uniqueness rules as
+// Register. The number can receive SMS login codes once verified, so changing
+// it takes the current password.
+func (s *authService) UpdatePhoneNumber(ctx context.Context, userID model.ID, phoneNumber, currentPassword string, client clientinfo.Info) (*model.User, error) {
+	var value *string
+	if phoneNumber != "" {
+		normalized, err := validation.NormalizePhoneNumber(phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		value = &normalized
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("authService.UpdatePhoneNumber (user): %w", err)
+	}
+
+	if err := s.checkCurrentPassword(ctx, user, currentPassword, client); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePhoneNumber(ctx, userID, value); err != nil {
+		return nil, fmt.Errorf("authService.UpdatePhoneNumber (repo): %w", err)
+	}
+
+	// A new number has to be verified again before it can receive SMS
+	// login codes.
+	if !equalStringPtr(user.PhoneNumber, value) {
+		user.PhoneVerifiedAt = nil
+	}
+	user.PhoneNumber = value
+
+	return user, nil
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/internal/validation"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/hash"
+)
+
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+var ErrUnsupportedImportFormat = errors.New("unsupported import format")
+
+// ImportFileError is returned when the file as a whole cannot be read, such
+// as a CSV file without a usable header row. Nothing is imported.
+type ImportFileError struct {
+	Message string
+}
+
+func (e *ImportFileError) Error() string {
+	return e.Message
+}
+
+// ImportedUser is one record of an import. PasswordHash must be in a format
+// known to pkg/hash; it is upgraded to the current scheme on first login.
+// CSV files carry the same fields as a header row.
+type ImportedUser struct {
+	Email         string `json:"email"`
+	PasswordHash  string `json:"password_hash"`
+	EmailVerified bool   `json:"email_verified"`
+	// CreatedAt is RFC 3339; the import time is used when empty.
+	CreatedAt string `json:"created_at"`
+}
+
+type ImportError struct {
+	Line    int
+	Email   string
+	Message string
+}
+
+type ImportResult struct {
+	Imported int
+	Skipped  int
+	Errors   []ImportError
+}
+
+// UserImportService creates users with pre-hashed passwords migrated from
+// other systems. Invalid records and existing emails are skipped and
+// reported; the rest are imported.
+type UserImportService interface {
+	Import(ctx context.Context, r io.Reader, format string) (*ImportResult, error)
+}
+
+type userImportService struct {
+	repo   repository.UserRepository
+	emails validation.EmailNormalizer
+}
+
+func NewUserImportService(repo repository.UserRepository, emails validation.EmailNormalizer) UserImportService {
+	return &userImportService{repo: repo, emails: emails}
+}
+
+func (s *userImportService) Import(ctx context.Context, r io.Reader, format string) (*ImportResult, error) {
+	result := &ImportResult{}
+
+	// add imports one record. A non-nil parseErr means the record could not
+	// be read and is only reported.
+	add := func(line int, record ImportedUser, parseErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := parseErr
+		if err == nil {
+			err = s.importUser(ctx, record)
+		}
+		if err == nil {
+			result.Imported++
+			return nil
+		}
+
+		var invalid *invalidImportError
+		switch {
+		case parseErr != nil:
+			err = errors.New("malformed record")
+		case errors.As(err, &invalid):
+		case errors.Is(err, repository.ErrUniqueConstraint):
+			err = errors.New("email already exists")
+		default:
+			return fmt.Errorf("userImportService.Import (line %d): %w", line, err)
+		}
+
+		result.Skipped++
+		result.Errors = append(result.Errors, ImportError{Line: line, Email: record.Email, Message: err.Error()})
+		return nil
+	}
+
+	var err error
+	switch format {
+	case ImportFormatCSV:
+		err = readImportCSV(r, add)
+	case ImportFormatJSONL:
+		err = readImportJSONL(r, add)
+	default:
+		return nil, ErrUnsupportedImportFormat
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// invalidImportError marks a record that was skipped for its content.
+type invalidImportError struct {
+	msg string
+}
+
+func (e *invalidImportError) Error() string {
+	return e.msg
+}
+
+func (s *userImportService) importUser(ctx context.Context, record ImportedUser) error {
+	email, err := s.emails.Normalize(record.Email)
+	if err != nil {
+		return &invalidImportError{"invalid email"}
+	}
+	if _, err := mail.ParseAddress(email); err != nil || strings.ContainsAny(email, "<> ") {
+		return &invalidImportError{"invalid email"}
+	}
+
+	if !hash.Supported(record.PasswordHash) {
+		return &invalidImportError{"unsupported password hash format"}
+	}
+
+	now := model.NewTimestamp()
+
+	createdAt := now
+	if record.CreatedAt != "" {
+		t, err := time.Parse(time.RFC3339, record.CreatedAt)
+		if err != nil {
+			return &invalidImportError{"invalid created_at"}
+		}
+		createdAt = t.UTC()
+	}
+
+	user := &model.User{
+		ID:           model.NewID(),
+		Email:        email,
+		PasswordHash: record.PasswordHash,
+		CreatedAt:    createdAt,
+	}
+	if record.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+
+	return s.repo.Create(ctx, user)
+}
+
+func readImportJSONL(r io.Reader, add func(int, ImportedUser, error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var record ImportedUser
+		parseErr := json.Unmarshal([]byte(text), &record)
+
+		if err := add(line, record, parseErr); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("readImportJSONL: %w", err)
+	}
+
+	return nil
+}
+
+func readImportCSV(r io.Reader, add func(int, ImportedUser, error) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		switch {
+		case errors.Is(err, io.EOF):
+			return &ImportFileError{"missing csv header"}
+		case errors.As(err, &parseErr):
+			return &ImportFileError{"malformed csv header"}
+		}
+		return fmt.Errorf("readImportCSV (header): %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"email", "password_hash"} {
+		if _, ok := columns[required]; !ok {
+			return &ImportFileError{fmt.Sprintf("missing %q column", required)}
+		}
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				if err := add(parseErr.Line, ImportedUser{}, parseErr); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("readImportCSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		verified, _ := strconv.ParseBool(field(row, "email_verified"))
+
+		err = add(line, ImportedUser{
+			Email:         field(row, "email"),
+			PasswordHash:  field(row, "password_hash"),
+			EmailVerified: verified,
+			CreatedAt:     field(row, "created_at"),
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+}
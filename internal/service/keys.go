@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/encrypt"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
+)
+
+type KeyService interface {
+	// Load refreshes the key ring from the database, activating a pending key
+	// whose publish delay has passed and creating the first key if none exists
+	// yet.
+	Load(ctx context.Context) error
+	// Rotate stores a new pending key. It is published straight away but only
+	// starts signing once the publish delay has passed.
+	Rotate(ctx context.Context) (*KeyRotation, error)
+	// RotateIfDue rotates only when the active key is older than maxAge and no
+	// key is pending, so that several instances running the same schedule
+	// rotate once.
+	RotateIfDue(ctx context.Context, maxAge time.Duration) error
+}
+
+type KeyRotation struct {
+	ActiveKeyID  string
+	PendingKeyID string
+	ActivatesAt  time.Time
+}
+
+type keyService struct {
+	repo         repository.SigningKeyRepository
+	ring         *token.KeyRing
+	cipher       *encrypt.Cipher
+	algorithm    string
+	gracePeriod  time.Duration
+	publishDelay time.Duration
+	initial      token.Signer
+
+	mu              sync.Mutex
+	activeCreatedAt time.Time
+	pending         bool
+}
+
+// NewKeyService manages the signing keys held by ring. Retiring keys are kept
+// for gracePeriod, which is raised to the access token lifetime if shorter.
+// Rotated keys stay pending for publishDelay, which is raised to the JWKS
+// cache lifetime if shorter. initial, if not nil, is stored as the first key
+// instead of generating one.
+func NewKeyService(repo repository.SigningKeyRepository, ring *token.KeyRing, cipher *encrypt.Cipher, algorithm string, gracePeriod, publishDelay time.Duration, initial token.Signer) KeyService {
+	if gracePeriod < token.AccessTokenTTL {
+		gracePeriod = token.AccessTokenTTL
+	}
+	if publishDelay < token.JWKSMaxAge {
+		publishDelay = token.JWKSMaxAge
+	}
+
+	return &keyService{
+		repo:         repo,
+		ring:         ring,
+		cipher:       cipher,
+		algorithm:    algorithm,
+		gracePeriod:  gracePeriod,
+		publishDelay: publishDelay,
+		initial:      initial,
+	}
+}
+
+func (s *keyService) Load(ctx context.Context) error {
+	now := model.NewTimestamp()
+
+	if err := s.repo.RetireExpired(ctx, now.Add(-s.gracePeriod)); err != nil {
+		return fmt.Errorf("keyService.Load (retire): %w", err)
+	}
+
+	promoted, err := s.repo.Promote(ctx, now.Add(-s.publishDelay), now.Add(-s.gracePeriod))
+	if err != nil {
+		return fmt.Errorf("keyService.Load (promote): %w", err)
+	}
+
+	loaded, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	if loaded {
+		if promoted {
+			log.Printf("INFO: signing key activated, active kid %s", s.ring.SigningKey().KeyID())
+		}
+		return nil
+	}
+
+	// Nothing has been signed yet, so the first key can be active at once.
+	if _, err := s.create(ctx, s.initial, model.SigningKeyActive); err != nil {
+		return err
+	}
+
+	loaded, err = s.load(ctx)
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		return errors.New("keyService.Load: no active signing key after rotation")
+	}
+
+	return nil
+}
+
+func (s *keyService) Rotate(ctx context.Context) (*KeyRotation, error) {
+	key, err := s.create(ctx, nil, model.SigningKeyPending)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Load(ctx); err != nil {
+		return nil, err
+	}
+
+	rotation := &KeyRotation{
+		ActiveKeyID:  s.ring.SigningKey().KeyID(),
+		PendingKeyID: key.ID,
+		ActivatesAt:  key.CreatedAt.Add(s.publishDelay),
+	}
+	log.Printf("INFO: signing key rotated, pending kid %s active from %s", rotation.PendingKeyID, rotation.ActivatesAt.Format(time.RFC3339))
+
+	return rotation, nil
+}
+
+func (s *keyService) RotateIfDue(ctx context.Context, maxAge time.Duration) error {
+	s.mu.Lock()
+	createdAt, pending := s.activeCreatedAt, s.pending
+	s.mu.Unlock()
+
+	if pending || time.Since(createdAt) < maxAge {
+		return s.Load(ctx)
+	}
+
+	_, err := s.Rotate(ctx)
+	// Another instance rotated concurrently; its key wins.
+	if errors.Is(err, ErrKeyRotationPending) {
+		return s.Load(ctx)
+	}
+
+	return err
+}
+
+// load fills the ring from the usable keys. It reports false when there is no
+// active key yet.
+func (s *keyService) load(ctx context.Context) (bool, error) {
+	keys, err := s.repo.ListUsable(ctx)
+	if err != nil {
+		return false, fmt.Errorf("keyService.load (list): %w", err)
+	}
+
+	var (
+		active          token.Signer
+		activeCreatedAt time.Time
+		pending         []token.Signer
+		retiring        []token.Signer
+	)
+
+	for _, key := range keys {
+		signer, err := s.decode(key)
+		if err != nil {
+			return false, fmt.Errorf("keyService.load (decode %s): %w", key.ID, err)
+		}
+
+		switch key.State {
+		case model.SigningKeyActive:
+			active, activeCreatedAt = signer, key.CreatedAt
+		case model.SigningKeyPending:
+			pending = append(pending, signer)
+		default:
+			retiring = append(retiring, signer)
+		}
+	}
+
+	if active == nil {
+		return false, nil
+	}
+
+	s.ring.Set(active, pending, retiring)
+
+	s.mu.Lock()
+	s.activeCreatedAt = activeCreatedAt
+	s.pending = len(pending) > 0
+	s.mu.Unlock()
+
+	return true, nil
+}
+
+// create stores signer, or a newly generated key if nil, in the given state.
+// A pending key that loses to a concurrent rotation returns
+// ErrKeyRotationPending; an active one defers to the winner silently.
+func (s *keyService) create(ctx context.Context, signer token.Signer, state string) (*model.SigningKey, error) {
+	if signer == nil {
+		var err error
+		signer, err = token.GenerateSigner(s.algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("keyService.create (generate): %w", err)
+		}
+	}
+
+	pemBytes, err := token.MarshalSignerPEM(signer)
+	if err != nil {
+		return nil, fmt.Errorf("keyService.create (marshal): %w", err)
+	}
+
+	sealed, err := s.cipher.Encrypt(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("keyService.create (encrypt): %w", err)
+	}
+
+	key := &model.SigningKey{
+		ID:         signer.KeyID(),
+		Algorithm:  signer.Method().Alg(),
+		PrivateKey: sealed,
+		State:      state,
+		CreatedAt:  model.NewTimestamp(),
+	}
+
+	err = s.repo.Create(ctx, key)
+	if err != nil {
+		if errors.Is(err, repository.ErrUniqueConstraint) {
+			if state == model.SigningKeyPending {
+				return nil, ErrKeyRotationPending
+			}
+			return key, nil
+		}
+		return nil, fmt.Errorf("keyService.create (store): %w", err)
+	}
+
+	return key, nil
+}
+
+func (s *keyService) decode(key *model.SigningKey) (token.Signer, error) {
+	pemBytes, err := s.cipher.Decrypt(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return token.ParseSignerPEM(pemBytes)
+}
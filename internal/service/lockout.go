@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// lockoutMemory is how long past lockouts are remembered when computing the
+// next lockout duration.
+const lockoutMemory = 24 * time.Hour
+
+// LockoutLimit configures brute-force protection for one kind of key.
+type LockoutLimit struct {
+	// MaxFailures is the number of failed logins within Window that starts a
+	// lockout. Zero disables the limit.
+	MaxFailures int
+	Window      time.Duration
+}
+
+// LockoutPolicy holds the thresholds for logins. Every lockout within
+// lockoutMemory doubles the previous one, starting at BaseLockout and capped
+// at MaxLockout.
+type LockoutPolicy struct {
+	PerEmail    LockoutLimit
+	PerIP       LockoutLimit
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// LockoutError is returned while logins for an account or client address are
+// locked.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("too many failed logins, retry after %s", e.RetryAfter)
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// LockoutService counts failed logins per email and per client IP in Redis.
+type LockoutService interface {
+	// Check returns a *LockoutError if either the email or the IP is locked.
+	Check(ctx context.Context, email, ip string) error
+	// RecordFailure counts a failed login and returns a *LockoutError if it
+	// started a lockout.
+	RecordFailure(ctx context.Context, email, ip string) error
+	RecordSuccess(ctx context.Context, email string) error
+	UnlockUser(ctx context.Context, userID model.ID) error
+}
+
+type lockoutService struct {
+	userRepo repository.UserRepository
+	redis    *redis.Client
+	policy   LockoutPolicy
+}
+
+func NewLockoutService(userRepo repository.UserRepository, redis *redis.Client, policy LockoutPolicy) LockoutService {
+	return &lockoutService{userRepo: userRepo, redis: redis, policy: policy}
+}
+
+type lockoutSubject struct {
+	kind  string
+	value string
+	limit LockoutLimit
+}
+
+func (s *lockoutService) subjects(email, ip string) []lockoutSubject {
+	subjects := make([]lockoutSubject, 0, 2)
+
+	if email = normalizeLockoutEmail(email); email != "" && s.policy.PerEmail.MaxFailures > 0 {
+		subjects = append(subjects, lockoutSubject{"email", email, s.policy.PerEmail})
+	}
+	if ip != "" && s.policy.PerIP.MaxFailures > 0 {
+		subjects = append(subjects, lockoutSubject{"ip", ip, s.policy.PerIP})
+	}
+
+	return subjects
+}
+
+func normalizeLockoutEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginFailuresKey(kind, value string) string {
+	return fmt.Sprintf("login_failures:%s:%s", kind, value)
+}
+
+func loginLockoutKey(kind, value string) string {
+	return fmt.Sprintf("login_lockout:%s:%s", kind, value)
+}
+
+func loginLockoutCountKey(kind, value string) string {
+	return fmt.Sprintf("login_lockouts:%s:%s", kind, value)
+}
+
+func (s *lockoutService) Check(ctx context.Context, email, ip string) error {
+	subjects := s.subjects(email, ip)
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	pipe := s.redis.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(subjects))
+	for i, subject := range subjects {
+		ttls[i] = pipe.PTTL(ctx, loginLockoutKey(subject.kind, subject.value))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("lockoutService.Check (redis): %w", err)
+	}
+
+	var retryAfter time.Duration
+	for _, ttl := range ttls {
+		retryAfter = max(retryAfter, ttl.Val())
+	}
+
+	if retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func (s *lockoutService) RecordFailure(ctx context.Context, email, ip string) error {
+	var lockout *LockoutError
+
+	for _, subject := range s.subjects(email, ip) {
+		locked, err := s.recordFailure(ctx, subject)
+		if err != nil {
+			return err
+		}
+		if locked > 0 && (lockout == nil || locked > lockout.RetryAfter) {
+			lockout = &LockoutError{RetryAfter: locked}
+		}
+	}
+
+	if lockout != nil {
+		return lockout
+	}
+
+	return nil
+}
+
+// recordFailure returns the lockout duration if this failure crossed the
+// threshold, and zero otherwise.
+func (s *lockoutService) recordFailure(ctx context.Context, subject lockoutSubject) (time.Duration, error) {
+	failuresKey := loginFailuresKey(subject.kind, subject.value)
+
+	pipe := s.redis.TxPipeline()
+	failures := pipe.Incr(ctx, failuresKey)
+	pipe.ExpireNX(ctx, failuresKey, subject.limit.Window)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("lockoutService.recordFailure (redis incr): %w", err)
+	}
+
+	if failures.Val() < int64(subject.limit.MaxFailures) {
+		return 0, nil
+	}
+
+	countKey := loginLockoutCountKey(subject.kind, subject.value)
+
+	pipe = s.redis.TxPipeline()
+	lockouts := pipe.Incr(ctx, countKey)
+	pipe.Expire(ctx, countKey, lockoutMemory)
+	pipe.Del(ctx, failuresKey)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("lockoutService.recordFailure (redis lockouts): %w", err)
+	}
+
+	duration := s.lockoutDuration(lockouts.Val())
+
+	err := s.redis.Set(ctx, loginLockoutKey(subject.kind, subject.value), 1, duration).Err()
+	if err != nil {
+		return 0, fmt.Errorf("lockoutService.recordFailure (redis lock): %w", err)
+	}
+
+	return duration, nil
+}
+
+func (s *lockoutService) lockoutDuration(lockouts int64) time.Duration {
+	duration := s.policy.BaseLockout
+	for i := int64(1); i < lockouts && duration < s.policy.MaxLockout; i++ {
+		duration *= 2
+	}
+
+	return min(duration, s.policy.MaxLockout)
+}
+
+// RecordSuccess clears the failure count of the email. The IP counter is left
+// alone so that one valid account cannot be used to reset guesses against
+// others.
+func (s *lockoutService) RecordSuccess(ctx context.Context, email string) error {
+	email = normalizeLockoutEmail(email)
+
+	if err := s.redis.Del(ctx, loginFailuresKey("email", email)).Err(); err != nil {
+		return fmt.Errorf("lockoutService.RecordSuccess (redis del): %w", err)
+	}
+
+	return nil
+}
+
+func (s *lockoutService) UnlockUser(ctx context.Context, userID model.ID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("lockoutService.UnlockUser (user): %w", err)
+	}
+
+	email := normalizeLockoutEmail(user.Email)
+
+	err = s.redis.Del(ctx,
+		loginFailuresKey("email", email),
+		loginLockoutKey("email", email),
+		loginLockoutCountKey("email", email),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("lockoutService.UnlockUser (redis del): %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/encrypt"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/hash"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/totp"
+	"github.com/redis/go-redis/v9"
+)
+
+// totpSkew accepts codes from one step before and after the current one to
+// tolerate clock drift on the user's device.
+const totpSkew = 1
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 15
+	recoveryCodeGroup    = 5
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAProof is the second factor presented to complete a login. Exactly one
+// field is expected to be set.
+type MFAProof struct {
+	TOTPCode     string
+	RecoveryCode string
+	// EmailCode is a code from RequestMFAEmailCode. It is checked by the
+	// AuthService rather than the MFAService.
+	EmailCode string
+	// SMSCode is a code from RequestMFASMSCode, also checked by the
+	// AuthService.
+	SMSCode string
+}
+
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, userID model.ID) (*TOTPEnrollment, error)
+	// ConfirmTOTP turns on two-factor authentication and returns the user's
+	// recovery codes, which are never shown again.
+	ConfirmTOTP(ctx context.Context, userID model.ID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID model.ID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID model.ID, code string) ([]string, error)
+	// IsEnabled reports whether the user has a confirmed TOTP secret or a
+	// registered passkey.
+	IsEnabled(ctx context.Context, userID model.ID) (bool, error)
+	// Verify checks a second factor. TOTP codes and recovery codes are each
+	// accepted only once; recovery codes only while TOTP is enabled.
+	Verify(ctx context.Context, userID model.ID, proof MFAProof) error
+}
+
+type mfaService struct {
+	repo          repository.TOTPRepository
+	recoveryCodes repository.RecoveryCodeRepository
+	passkeys      repository.WebAuthnCredentialRepository
+	userRepo      repository.UserRepository
+	redis         *redis.Client
+	cipher        *encrypt.Cipher
+	issuer        string
+}
+
+func NewMFAService(repo repository.TOTPRepository, recoveryCodes repository.RecoveryCodeRepository, passkeys repository.WebAuthnCredentialRepository, userRepo repository.UserRepository, redis *redis.Client, cipher *encrypt.Cipher, issuer string) MFAService {
+	return &mfaService{repo: repo, recoveryCodes: recoveryCodes, passkeys: passkeys, userRepo: userRepo, redis: redis, cipher: cipher, issuer: issuer}
+}
+
+func (s *mfaService) EnrollTOTP(ctx context.Context, userID model.ID) (*TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.EnrollTOTP (user): %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.EnrollTOTP (generate): %w", err)
+	}
+
+	sealed, err := s.cipher.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.EnrollTOTP (encrypt): %w", err)
+	}
+
+	err = s.repo.Upsert(ctx, &model.TOTP{
+		UserID:    userID,
+		Secret:    sealed,
+		CreatedAt: model.NewTimestamp(),
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrUniqueConstraint) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, fmt.Errorf("mfaService.EnrollTOTP (store): %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+func (s *mfaService) ConfirmTOTP(ctx context.Context, userID model.ID, code string) ([]string, error) {
+	record, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, fmt.Errorf("mfaService.ConfirmTOTP (get): %w", err)
+	}
+
+	if record.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := s.checkCode(ctx, record, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Confirm(ctx, userID, model.NewTimestamp()); err != nil {
+		return nil, fmt.Errorf("mfaService.ConfirmTOTP (confirm): %w", err)
+	}
+
+	return codes, nil
+}
+
+func (s *mfaService) DisableTOTP(ctx context.Context, userID model.ID, code string) error {
+	if err := s.verifyTOTP(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("mfaService.DisableTOTP (delete): %w", err)
+	}
+
+	if err := s.recoveryCodes.DeleteAll(ctx, userID); err != nil {
+		return fmt.Errorf("mfaService.DisableTOTP (recovery codes): %w", err)
+	}
+
+	return nil
+}
+
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID model.ID, code string) ([]string, error) {
+	if err := s.verifyTOTP(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+func (s *mfaService) IsEnabled(ctx context.Context, userID model.ID) (bool, error) {
+	record, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return false, fmt.Errorf("mfaService.IsEnabled (totp): %w", err)
+	}
+	if err == nil && record.ConfirmedAt != nil {
+		return true, nil
+	}
+
+	passkeys, err := s.passkeys.ListByUser(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("mfaService.IsEnabled (passkeys): %w", err)
+	}
+
+	return len(passkeys) > 0, nil
+}
+
+func (s *mfaService) Verify(ctx context.Context, userID model.ID, proof MFAProof) error {
+	if proof.RecoveryCode != "" {
+		return s.useRecoveryCode(ctx, userID, proof.RecoveryCode)
+	}
+
+	return s.verifyTOTP(ctx, userID, proof.TOTPCode)
+}
+
+func (s *mfaService) verifyTOTP(ctx context.Context, userID model.ID, code string) error {
+	record, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrMFANotEnrolled
+		}
+		return fmt.Errorf("mfaService.verifyTOTP (get): %w", err)
+	}
+
+	if record.ConfirmedAt == nil {
+		return ErrMFANotEnrolled
+	}
+
+	return s.checkCode(ctx, record, code)
+}
+
+func (s *mfaService) checkCode(ctx context.Context, record *model.TOTP, code string) error {
+	secret, err := s.cipher.Decrypt(record.Secret)
+	if err != nil {
+		return fmt.Errorf("mfaService.checkCode (decrypt): %w", err)
+	}
+
+	step, ok := totp.Validate(code, string(secret), time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	key := fmt.Sprintf("totp_used:%s:%d", record.UserID, step)
+	fresh, err := s.redis.SetNX(ctx, key, 1, time.Duration(2*totpSkew+1)*totp.Period).Result()
+	if err != nil {
+		return fmt.Errorf("mfaService.checkCode (redis setnx): %w", err)
+	}
+
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// useRecoveryCode spends one of the user's recovery codes. Codes are issued
+// when TOTP is confirmed, replaced by proving a TOTP code and deleted with
+// TOTP, so they are only accepted while TOTP is enabled; a passkey-only
+// account has none to use.
+func (s *mfaService) useRecoveryCode(ctx context.Context, userID model.ID, code string) error {
+	record, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrMFANotEnrolled
+		}
+		return fmt.Errorf("mfaService.useRecoveryCode (totp): %w", err)
+	}
+	if record.ConfirmedAt == nil {
+		return ErrMFANotEnrolled
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return ErrInvalidMFACode
+	}
+
+	// The first group only narrows the lookup; the rest of the code is what
+	// the stored hash protects.
+	codes, err := s.recoveryCodes.ListUnused(ctx, userID, normalized[:recoveryCodeGroup])
+	if err != nil {
+		return fmt.Errorf("mfaService.useRecoveryCode (list): %w", err)
+	}
+
+	for _, c := range codes {
+		if !hash.CheckPasswordHash(normalized, c.CodeHash) {
+			continue
+		}
+
+		err := s.recoveryCodes.MarkUsed(ctx, c.ID, model.NewTimestamp())
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrInvalidMFACode
+			}
+			return fmt.Errorf("mfaService.useRecoveryCode (mark used): %w", err)
+		}
+
+		return nil
+	}
+
+	return ErrInvalidMFACode
+}
+
+func (s *mfaService) replaceRecoveryCodes(ctx context.Context, userID model.ID) ([]string, error) {
+	now := model.NewTimestamp()
+	plain := make([]string, 0, recoveryCodeCount)
+	records := make([]*model.RecoveryCode, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("mfaService.replaceRecoveryCodes (generate): %w", err)
+		}
+
+		normalized := normalizeRecoveryCode(code)
+
+		codeHash, err := hash.HashPassword(normalized)
+		if err != nil {
+			return nil, fmt.Errorf("mfaService.replaceRecoveryCodes (hash): %w", err)
+		}
+
+		plain = append(plain, code)
+		records = append(records, &model.RecoveryCode{
+			ID:         model.NewID(),
+			UserID:     userID,
+			CodePrefix: normalized[:recoveryCodeGroup],
+			CodeHash:   codeHash,
+			CreatedAt:  now,
+		})
+	}
+
+	if err := s.recoveryCodes.Replace(ctx, userID, records); err != nil {
+		return nil, fmt.Errorf("mfaService.replaceRecoveryCodes (store): %w", err)
+	}
+
+	return plain, nil
+}
+
+// generateRecoveryCode returns a code such as "k7d2m-q9xhp-a3fzn" drawn from
+// an alphabet without look-alike characters.
+func generateRecoveryCode() (string, error) {
+	// Bytes at or above limit are rejected so every character is equally likely.
+	limit := 256 - 256%len(recoveryCodeAlphabet)
+
+	var sb strings.Builder
+	buf := make([]byte, 1)
+
+	for n := 0; n < recoveryCodeLength; {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		if int(buf[0]) >= limit {
+			continue
+		}
+
+		if n > 0 && n%recoveryCodeGroup == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeAlphabet[int(buf[0])%len(recoveryCodeAlphabet)])
+		n++
+	}
+
+	return sb.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
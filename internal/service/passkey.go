@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+)
+
+const passkeyCeremonyTTL = 5 * time.Minute
+
+// PasskeyCeremony is handed to the client to run navigator.credentials.create
+// or navigator.credentials.get. SessionToken must be sent back with the result.
+type PasskeyCeremony struct {
+	Options      json.RawMessage
+	SessionToken string
+}
+
+type PasskeyService interface {
+	BeginRegistration(ctx context.Context, userID model.ID) (*PasskeyCeremony, error)
+	FinishRegistration(ctx context.Context, userID model.ID, sessionToken, name string, response []byte) (*model.WebAuthnCredential, error)
+	// BeginLogin starts an assertion. With a nil userID any discoverable
+	// passkey is accepted and user verification is required, making it a
+	// complete login on its own. mfaToken is stored with the ceremony and
+	// returned by FinishLogin.
+	BeginLogin(ctx context.Context, userID *model.ID, mfaToken string) (*PasskeyCeremony, error)
+	FinishLogin(ctx context.Context, sessionToken string, response []byte) (model.ID, string, error)
+}
+
+type passkeyService struct {
+	repo     repository.WebAuthnCredentialRepository
+	userRepo repository.UserRepository
+	redis    *redis.Client
+	webauthn *webauthn.WebAuthn
+}
+
+func NewPasskeyService(repo repository.WebAuthnCredentialRepository, userRepo repository.UserRepository, redis *redis.Client, webauthn *webauthn.WebAuthn) PasskeyService {
+	return &passkeyService{repo: repo, userRepo: userRepo, redis: redis, webauthn: webauthn}
+}
+
+// passkeyCeremonyState is what is kept in Redis between the begin and finish
+// calls of a ceremony.
+type passkeyCeremonyState struct {
+	Session  webauthn.SessionData `json:"session"`
+	MFAToken string               `json:"mfa_token,omitempty"`
+}
+
+// webauthnUser adapts a user and their stored credentials to webauthn.User.
+type webauthnUser struct {
+	user        *model.User
+	credentials []*model.WebAuthnCredential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	id := u.user.ID
+	return id[:]
+}
+
+func (u *webauthnUser) WebAuthnName() string        { return u.user.Email }
+func (u *webauthnUser) WebAuthnDisplayName() string { return u.user.Email }
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+
+	for _, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+		for _, t := range c.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              c.ID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
+
+func (s *passkeyService) loadUser(ctx context.Context, userID model.ID) (*webauthnUser, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webauthnUser{user: user, credentials: credentials}, nil
+}
+
+func (s *passkeyService) BeginRegistration(ctx context.Context, userID model.ID) (*PasskeyCeremony, error) {
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.BeginRegistration (user): %w", err)
+	}
+
+	// Passkeys must be discoverable so they can be used without a password.
+	creation, session, err := s.webauthn.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.BeginRegistration (begin): %w", err)
+	}
+
+	return s.saveCeremony(ctx, creation, &passkeyCeremonyState{Session: *session})
+}
+
+func (s *passkeyService) FinishRegistration(ctx context.Context, userID model.ID, sessionToken, name string, response []byte) (*model.WebAuthnCredential, error) {
+	state, err := s.takeCeremony(ctx, sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.FinishRegistration (user): %w", err)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	// CreateCredential checks that the ceremony was started for this user.
+	credential, err := s.webauthn.CreateCredential(user, state.Session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	record := &model.WebAuthnCredential{
+		ID:              credential.ID,
+		UserID:          userID,
+		Name:            name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       model.NewTimestamp(),
+	}
+
+	if err := s.repo.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("passkeyService.FinishRegistration (store): %w", err)
+	}
+
+	return record, nil
+}
+
+func (s *passkeyService) BeginLogin(ctx context.Context, userID *model.ID, mfaToken string) (*PasskeyCeremony, error) {
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+		err       error
+	)
+
+	if userID == nil {
+		assertion, session, err = s.webauthn.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired),
+		)
+	} else {
+		var user *webauthnUser
+		user, err = s.loadUser(ctx, *userID)
+		if err != nil {
+			return nil, fmt.Errorf("passkeyService.BeginLogin (user): %w", err)
+		}
+		if len(user.credentials) == 0 {
+			return nil, ErrMFANotEnrolled
+		}
+		assertion, session, err = s.webauthn.BeginLogin(user)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.BeginLogin (begin): %w", err)
+	}
+
+	return s.saveCeremony(ctx, assertion, &passkeyCeremonyState{Session: *session, MFAToken: mfaToken})
+}
+
+func (s *passkeyService) FinishLogin(ctx context.Context, sessionToken string, response []byte) (model.ID, string, error) {
+	state, err := s.takeCeremony(ctx, sessionToken)
+	if err != nil {
+		return model.ID{}, "", err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return model.ID{}, "", fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	var user *webauthnUser
+
+	if len(state.Session.UserID) == 0 {
+		// Discoverable login: the authenticator tells us who the user is.
+		handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+			userID, err := model.FromBytes(userHandle)
+			if err != nil {
+				return nil, err
+			}
+			user, err = s.loadUser(ctx, userID)
+			return user, err
+		}
+
+		if _, err := s.webauthn.ValidateDiscoverableLogin(handler, state.Session, parsed); err != nil {
+			return model.ID{}, "", fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+		}
+	} else {
+		userID, err := model.FromBytes(state.Session.UserID)
+		if err != nil {
+			return model.ID{}, "", fmt.Errorf("passkeyService.FinishLogin (user id): %w", err)
+		}
+
+		user, err = s.loadUser(ctx, userID)
+		if err != nil {
+			return model.ID{}, "", fmt.Errorf("passkeyService.FinishLogin (user): %w", err)
+		}
+
+		if _, err := s.webauthn.ValidateLogin(user, state.Session, parsed); err != nil {
+			return model.ID{}, "", fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+		}
+	}
+
+	s.recordUse(ctx, user, parsed)
+
+	return user.user.ID, state.MFAToken, nil
+}
+
+// recordUse stores the new signature counter. A counter that did not move
+// forward hints at a cloned authenticator; it is logged rather than rejected
+// because synced passkeys always report zero.
+func (s *passkeyService) recordUse(ctx context.Context, user *webauthnUser, parsed *protocol.ParsedCredentialAssertionData) {
+	for _, c := range user.credentials {
+		if string(c.ID) != string(parsed.RawID) {
+			continue
+		}
+
+		counter := parsed.Response.AuthenticatorData.Counter
+		if counter != 0 && counter <= c.SignCount {
+			log.Printf("WARN: passkeyService: possible cloned authenticator for user %s", user.user.ID)
+		}
+
+		c.SignCount = counter
+		c.BackupState = parsed.Response.AuthenticatorData.Flags.HasBackupState()
+
+		if err := s.repo.UpdateAfterLogin(ctx, c, model.NewTimestamp()); err != nil {
+			log.Printf("WARN: passkeyService.recordUse: %v", err)
+		}
+		return
+	}
+}
+
+func passkeyCeremonyKey(sessionToken string) string {
+	return fmt.Sprintf("webauthn_session:%s", token.HashOpaqueToken(sessionToken))
+}
+
+func (s *passkeyService) saveCeremony(ctx context.Context, options any, state *passkeyCeremonyState) (*PasskeyCeremony, error) {
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.saveCeremony (options): %w", err)
+	}
+
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.saveCeremony (state): %w", err)
+	}
+
+	sessionToken, err := token.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.saveCeremony (token): %w", err)
+	}
+
+	err = s.redis.Set(ctx, passkeyCeremonyKey(sessionToken), stateJSON, passkeyCeremonyTTL).Err()
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.saveCeremony (redis set): %w", err)
+	}
+
+	return &PasskeyCeremony{Options: optionsJSON, SessionToken: sessionToken}, nil
+}
+
+// takeCeremony loads and deletes the ceremony state so that each challenge
+// can be answered once.
+func (s *passkeyService) takeCeremony(ctx context.Context, sessionToken string) (*passkeyCeremonyState, error) {
+	data, err := s.redis.GetDel(ctx, passkeyCeremonyKey(sessionToken)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidPasskeySession
+		}
+		return nil, fmt.Errorf("passkeyService.takeCeremony (redis getdel): %w", err)
+	}
+
+	var state passkeyCeremonyState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("passkeyService.takeCeremony (decode): %w", err)
+	}
+
+	return &state, nil
+}
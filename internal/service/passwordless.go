@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
+	"github.com/redis/go-redis/v9"
+)
+
+const magicLinkTTL = 15 * time.Minute
+
+// One-time codes are sent by email or SMS.
+const (
+	oneTimeCodeTTL         = 10 * time.Minute
+	oneTimeCodeDigits      = 6
+	oneTimeCodeMaxAttempts = 5
+)
+
+// Email codes are scoped by purpose so that a code sent for a passwordless
+// login cannot answer an MFA challenge and the other way around.
+const (
+	emailCodeLogin = "login"
+	emailCodeMFA   = "mfa"
+)
+
+func magicLinkKey(linkToken string) string {
+	return fmt.Sprintf("magic_link:%s", token.HashOpaqueToken(linkToken))
+}
+
+func (s *authService) RequestMagicLink(ctx context.Context, email string) error {
+	user, err := s.repo.GetByEmail(ctx, s.normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("authService.RequestMagicLink (user): %w", err)
+	}
+
+	linkToken, err := token.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("authService.RequestMagicLink (token): %w", err)
+	}
+
+	key := magicLinkKey(linkToken)
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, map[string]any{
+		"user_id": user.ID.String(),
+		"email":   user.Email,
+	})
+	pipe.Expire(ctx, key, magicLinkTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("authService.RequestMagicLink (redis): %w", err)
+	}
+
+	go func() {
+		err := s.emailService.SendMagicLink(user.Email, linkToken)
+		if err != nil {
+			log.Printf("ERROR: authService.RequestMagicLink background email: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// ConsumeMagicLink logs in the owner of the link. Opening the link proves
+// control of the address, so it also marks the email as verified.
+func (s *authService) ConsumeMagicLink(ctx context.Context, linkToken string, client clientinfo.Info) (*LoginResult, error) {
+	key := magicLinkKey(linkToken)
+
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("authService.ConsumeMagicLink (redis get): %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrInvalidMagicLink
+	}
+
+	deleted, err := s.redis.Del(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("authService.ConsumeMagicLink (redis del): %w", err)
+	}
+	if deleted == 0 {
+		// A concurrent request already used this link.
+		return nil, ErrInvalidMagicLink
+	}
+
+	userID, err := model.ParseID(fields["user_id"])
+	if err != nil {
+		return nil, fmt.Errorf("authService.ConsumeMagicLink (parseID): %w", err)
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, fmt.Errorf("authService.ConsumeMagicLink (user): %w", err)
+	}
+
+	// The link was sent to an address the account no longer uses.
+	if user.Email != fields["email"] {
+		return nil, ErrInvalidMagicLink
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := s.repo.MarkEmailVerified(ctx, userID, model.NewTimestamp()); err != nil {
+			return nil, fmt.Errorf("authService.ConsumeMagicLink (verify email): %w", err)
+		}
+	}
+
+	return s.completeLogin(ctx, userID, firstFactorEmail, client)
+}
+
+func (s *authService) RequestLoginCode(ctx context.Context, email string) error {
+	user, err := s.repo.GetByEmail(ctx, s.normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("authService.RequestLoginCode (user): %w", err)
+	}
+
+	return s.sendEmailCode(ctx, user, emailCodeLogin)
+}
+
+// VerifyLoginCode logs in with a code from RequestLoginCode. Like a magic
+// link, a correct code also marks the email as verified.
+func (s *authService) VerifyLoginCode(ctx context.Context, email, code string, client clientinfo.Info) (*LoginResult, error) {
+	user, err := s.repo.GetByEmail(ctx, s.normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidEmailCode
+		}
+		return nil, fmt.Errorf("authService.VerifyLoginCode (user): %w", err)
+	}
+
+	if err := s.checkEmailCode(ctx, user.ID, emailCodeLogin, code); err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := s.repo.MarkEmailVerified(ctx, user.ID, model.NewTimestamp()); err != nil {
+			return nil, fmt.Errorf("authService.VerifyLoginCode (verify email): %w", err)
+		}
+	}
+
+	return s.completeLogin(ctx, user.ID, firstFactorEmail, client)
+}
+
+// RequestMFAEmailCode sends a code that VerifyMFA accepts in place of the
+// user's second factor. Only verified addresses qualify.
+func (s *authService) RequestMFAEmailCode(ctx context.Context, mfaToken string) error {
+	challenge, err := s.getMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, challenge.userID)
+	if err != nil {
+		return fmt.Errorf("authService.RequestMFAEmailCode (user): %w", err)
+	}
+
+	if challenge.firstFactor == firstFactorEmail {
+		return ErrMFAFactorNotAllowed
+	}
+
+	if user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+
+	return s.sendEmailCode(ctx, user, emailCodeMFA)
+}
+
+func emailCodeKey(purpose string, userID model.ID) string {
+	return fmt.Sprintf("email_code:%s:%s", purpose, userID)
+}
+
+// sendEmailCode replaces any pending code for the same purpose.
+func (s *authService) sendEmailCode(ctx context.Context, user *model.User, purpose string) error {
+	code, err := s.storeCode(ctx, emailCodeKey(purpose, user.ID))
+	if err != nil {
+		return fmt.Errorf("authService.sendEmailCode: %w", err)
+	}
+
+	go func() {
+		err := s.emailService.SendLoginCode(user.Email, code, oneTimeCodeTTL)
+		if err != nil {
+			log.Printf("ERROR: authService.sendEmailCode background email: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *authService) checkEmailCode(ctx context.Context, userID model.ID, purpose, code string) error {
+	return s.checkCode(ctx, emailCodeKey(purpose, userID), code, ErrInvalidEmailCode)
+}
+
+// storeCode generates a one-time code and stores its hash under key,
+// replacing any pending code there.
+func (s *authService) storeCode(ctx context.Context, key string) (string, error) {
+	code, err := generateOneTimeCode()
+	if err != nil {
+		return "", fmt.Errorf("authService.storeCode (generate): %w", err)
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, map[string]any{
+		"code_hash": token.HashOpaqueToken(code),
+		"attempts":  0,
+	})
+	pipe.Expire(ctx, key, oneTimeCodeTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("authService.storeCode (redis): %w", err)
+	}
+
+	return code, nil
+}
+
+// checkCode accepts the code under key once, returning invalid otherwise.
+// Every attempt counts against the limit, after which the code is discarded
+// and a new one must be requested.
+func (s *authService) checkCode(ctx context.Context, key, code string, invalid error) error {
+	pipe := s.redis.TxPipeline()
+	attempts := pipe.HIncrBy(ctx, key, "attempts", 1)
+	// Guards against recreating a code that expired in the meantime without
+	// a TTL.
+	pipe.ExpireNX(ctx, key, oneTimeCodeTTL)
+	codeHash := pipe.HGet(ctx, key, "code_hash")
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("authService.checkCode (redis): %w", err)
+	}
+
+	if codeHash.Val() == "" || attempts.Val() > oneTimeCodeMaxAttempts {
+		if err := s.redis.Del(ctx, key).Err(); err != nil {
+			log.Printf("WARN: authService.checkCode (redis del): %v", err)
+		}
+		return invalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(codeHash.Val()), []byte(token.HashOpaqueToken(code))) != 1 {
+		return invalid
+	}
+
+	deleted, err := s.redis.Del(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("authService.checkCode (redis del): %w", err)
+	}
+	if deleted == 0 {
+		// A concurrent request already used this code.
+		return invalid
+	}
+
+	return nil
+}
+
+func generateOneTimeCode() (string, error) {
+	limit := big.NewInt(1)
+	for range oneTimeCodeDigits {
+		limit.Mul(limit, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", oneTimeCodeDigits, n), nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+)
+
+// SMS codes are scoped by purpose like email codes.
+const (
+	smsCodePhoneVerification = "phone_verification"
+	smsCodeMFA               = "mfa"
+)
+
+func smsCodeKey(purpose string, userID model.ID) string {
+	return fmt.Sprintf("sms_code:%s:%s", purpose, userID)
+}
+
+// RequestPhoneVerification texts a code that VerifyPhone accepts to confirm
+// the user's phone number.
+func (s *authService) RequestPhoneVerification(ctx context.Context, userID model.ID) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("authService.RequestPhoneVerification (user): %w", err)
+	}
+
+	if user.PhoneNumber == nil {
+		return ErrNoPhoneNumber
+	}
+
+	return s.sendSMSCode(ctx, user, smsCodePhoneVerification)
+}
+
+func (s *authService) VerifyPhone(ctx context.Context, userID model.ID, code string) error {
+	if err := s.checkCode(ctx, smsCodeKey(smsCodePhoneVerification, userID), code, ErrInvalidSMSCode); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("authService.VerifyPhone (user): %w", err)
+	}
+
+	if user.PhoneNumber == nil {
+		return ErrNoPhoneNumber
+	}
+
+	err = s.repo.MarkPhoneVerified(ctx, userID, *user.PhoneNumber, model.NewTimestamp())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// The number changed after the code was sent.
+			return ErrInvalidSMSCode
+		}
+		return fmt.Errorf("authService.VerifyPhone (repo): %w", err)
+	}
+
+	return nil
+}
+
+// RequestMFASMSCode texts a code that VerifyMFA accepts in place of the
+// user's second factor. Only verified phone numbers qualify.
+func (s *authService) RequestMFASMSCode(ctx context.Context, mfaToken string) error {
+	challenge, err := s.getMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, challenge.userID)
+	if err != nil {
+		return fmt.Errorf("authService.RequestMFASMSCode (user): %w", err)
+	}
+
+	if user.PhoneNumber == nil || user.PhoneVerifiedAt == nil {
+		return ErrPhoneNotVerified
+	}
+
+	return s.sendSMSCode(ctx, user, smsCodeMFA)
+}
+
+// sendSMSCode replaces any pending code for the same purpose.
+func (s *authService) sendSMSCode(ctx context.Context, user *model.User, purpose string) error {
+	code, err := s.storeCode(ctx, smsCodeKey(purpose, user.ID))
+	if err != nil {
+		return fmt.Errorf("authService.sendSMSCode: %w", err)
+	}
+
+	phoneNumber := *user.PhoneNumber
+
+	go func() {
+		if err := s.sms.SendCode(phoneNumber, code, oneTimeCodeTTL); err != nil {
+			log.Printf("ERROR: authService.sendSMSCode background sms: %v", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eduardovfaleiro/gatekeeper/internal/clientinfo"
+	"github.com/eduardovfaleiro/gatekeeper/internal/model"
+	"github.com/eduardovfaleiro/gatekeeper/internal/repository"
+	"github.com/eduardovfaleiro/gatekeeper/pkg/token"
+)
+
+// SessionService tracks logins. A session id doubles as the refresh token
+// family id and is carried in the sid claim of every access token, so revoking
+// a session cuts off both its refresh tokens and its access tokens.
+type SessionService interface {
+	Create(ctx context.Context, userID model.ID, client clientinfo.Info) (*model.Session, error)
+	Touch(ctx context.Context, sessionID model.ID) error
+	List(ctx context.Context, userID model.ID) ([]*model.Session, error)
+	Revoke(ctx context.Context, userID, sessionID model.ID) error
+	RevokeAll(ctx context.Context, userID model.ID) error
+	// RevokeOthers revokes every active session of the user except keep.
+	RevokeOthers(ctx context.Context, userID, keep model.ID) error
+}
+
+type sessionService struct {
+	repo          repository.SessionRepository
+	refreshTokens repository.RefreshTokenRepository
+	revocations   repository.TokenRevocationRepository
+}
+
+func NewSessionService(repo repository.SessionRepository, refreshTokens repository.RefreshTokenRepository, revocations repository.TokenRevocationRepository) SessionService {
+	return &sessionService{repo: repo, refreshTokens: refreshTokens, revocations: revocations}
+}
+
+func (s *sessionService) Create(ctx context.Context, userID model.ID, client clientinfo.Info) (*model.Session, error) {
+	now := model.NewTimestamp()
+	session := &model.Session{
+		ID:         model.NewID(),
+		UserID:     userID,
+		Device:     client.Device,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+
+	if err := s.repo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("sessionService.Create: %w", err)
+	}
+
+	return session, nil
+}
+
+func (s *sessionService) Touch(ctx context.Context, sessionID model.ID) error {
+	if err := s.repo.Touch(ctx, sessionID, model.NewTimestamp()); err != nil {
+		return fmt.Errorf("sessionService.Touch: %w", err)
+	}
+	return nil
+}
+
+func (s *sessionService) List(ctx context.Context, userID model.ID) ([]*model.Session, error) {
+	sessions, err := s.repo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("sessionService.List: %w", err)
+	}
+	return sessions, nil
+}
+
+func (s *sessionService) Revoke(ctx context.Context, userID, sessionID model.ID) error {
+	if err := s.repo.Revoke(ctx, userID, sessionID, model.NewTimestamp()); err != nil {
+		return fmt.Errorf("sessionService.Revoke (repo): %w", err)
+	}
+
+	if err := s.refreshTokens.RevokeFamily(ctx, sessionID); err != nil {
+		return fmt.Errorf("sessionService.Revoke (refresh): %w", err)
+	}
+
+	if err := s.revocations.RevokeSession(ctx, sessionID, token.AccessTokenTTL); err != nil {
+		return fmt.Errorf("sessionService.Revoke (access): %w", err)
+	}
+
+	return nil
+}
+
+func (s *sessionService) RevokeAll(ctx context.Context, userID model.ID) error {
+	now := model.NewTimestamp()
+
+	if err := s.repo.RevokeAllByUser(ctx, userID, now); err != nil {
+		return fmt.Errorf("sessionService.RevokeAll (repo): %w", err)
+	}
+
+	if err := s.refreshTokens.RevokeUserFamilies(ctx, userID); err != nil {
+		return fmt.Errorf("sessionService.RevokeAll (refresh): %w", err)
+	}
+
+	if err := s.revocations.RevokeUserTokens(ctx, userID, now, token.AccessTokenTTL); err != nil {
+		return fmt.Errorf("sessionService.RevokeAll (access): %w", err)
+	}
+
+	return nil
+}
+
+func (s *sessionService) RevokeOthers(ctx context.Context, userID, keep model.ID) error {
+	sessions, err := s.repo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("sessionService.RevokeOthers (list): %w", err)
+	}
+
+	for _, session := range sessions {
+		if session.ID == keep {
+			continue
+		}
+
+		if err := s.Revoke(ctx, userID, session.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("sessionService.RevokeOthers: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type SMSService interface {
+	SendCode(phoneNumber, code string, expiresIn time.Duration) error
+}
+
+var smsCodeTemplate = template.Must(template.New("sms_code").Parse(
+	`Your verification code is {{.Code}}. It expires in {{.Minutes}} minutes.`))
+
+func renderSMSCode(code string, expiresIn time.Duration) (string, error) {
+	var sb strings.Builder
+	err := smsCodeTemplate.Execute(&sb, loginCodeData{Code: code, Minutes: int(expiresIn.Minutes())})
+	return sb.String(), err
+}
+
+type consoleSMSService struct{}
+
+func (s *consoleSMSService) SendCode(phoneNumber, code string, expiresIn time.Duration) error {
+	body, err := renderSMSCode(code, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	println("SMS to " + phoneNumber + ": " + body)
+
+	return nil
+}
+
+func NewSMSService() SMSService {
+	return &consoleSMSService{}
+}
+
+// smsWebhookTimeout bounds a single delivery attempt to the webhook.
+const smsWebhookTimeout = 10 * time.Second
+
+// webhookSMSService posts each message as JSON to an HTTP endpoint, such as a
+// provider adapter or a local stub.
+type webhookSMSService struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type smsWebhookPayload struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+// NewWebhookSMSService sends messages to url. A non-empty token is sent as a
+// bearer token in the Authorization header.
+func NewWebhookSMSService(url, token string) SMSService {
+	return &webhookSMSService{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: smsWebhookTimeout},
+	}
+}
+
+func (s *webhookSMSService) SendCode(phoneNumber, code string, expiresIn time.Duration) error {
+	body, err := renderSMSCode(code, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	return s.send(phoneNumber, body)
+}
+
+func (s *webhookSMSService) send(to, body string) error {
+	payload, err := json.Marshal(smsWebhookPayload{To: to, Body: body})
+	if err != nil {
+		return fmt.Errorf("webhookSMSService.send (marshal): %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("webhookSMSService.send (request): %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhookSMSService.send (post): %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drained so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhookSMSService.send: webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidEmail = errors.New("invalid email address")
+
+// EmailNormalizer produces the form of an address that is stored in and
+// looked up from users.email. The column compares case-insensitively, so
+// normalizing mostly keeps the stored values tidy and comparable outside the
+// database.
+type EmailNormalizer struct {
+	// LowercaseLocalPart lower-cases the part before the @ as well. Mail
+	// servers may treat it case-sensitively, though very few do.
+	LowercaseLocalPart bool
+}
+
+// Normalize trims the address, lower-cases the domain and converts
+// internationalized domains to punycode.
+func (n EmailNormalizer) Normalize(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", ErrInvalidEmail
+	}
+
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+
+	domain, err := idna.Lookup.ToASCII(domain)
+	if err != nil || domain == "" {
+		return "", ErrInvalidEmail
+	}
+
+	if n.LowercaseLocalPart {
+		local = strings.ToLower(local)
+	}
+
+	return local + "@" + strings.ToLower(domain), nil
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrInvalidPhoneNumber = errors.New("invalid phone number")
+)
+
+// usernamePattern never matches an email address or a phone number, which
+// keeps login identifiers unambiguous.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.]{2,29}$`)
+
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// ValidateUsername accepts 3 to 30 letters, digits, dots and underscores,
+// starting with a letter or digit.
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// NormalizePhoneNumber strips common separators and returns the number in
+// E.164 format. The country code is required.
+func NormalizePhoneNumber(phone string) (string, error) {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, phone)
+
+	if !e164Pattern.MatchString(phone) {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return phone, nil
+}
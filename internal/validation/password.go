@@ -1,16 +1,204 @@
 package validation
 
 import (
-	"github.com/go-playground/validator/v10"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	passwordValidator "github.com/wagslane/go-password-validator"
+)
+
+// Reasons reported in a PasswordViolation.
+const (
+	ReasonTooShort         = "PASSWORD_TOO_SHORT"
+	ReasonTooLong          = "PASSWORD_TOO_LONG"
+	ReasonLowEntropy       = "PASSWORD_LOW_ENTROPY"
+	ReasonCharacterClasses = "PASSWORD_CHARACTER_CLASSES"
+	ReasonRepeated         = "PASSWORD_REPEATED_CHARACTERS"
+	ReasonContainsEmail    = "PASSWORD_CONTAINS_EMAIL"
+	ReasonBreached         = "PASSWORD_BREACHED"
+	ReasonReused           = "PASSWORD_REUSED"
 )
 
-var validate = validator.New()
+// minEmailPart is the shortest piece of an email address that a password may
+// not contain. Shorter pieces match too many unrelated passwords.
+const minEmailPart = 3
+
+// BreachChecker reports whether a password appears in a known breach corpus.
+type BreachChecker interface {
+	Contains(password string) bool
+}
+
+// PasswordPolicy holds the rules every new password must pass. Zero values
+// disable a rule.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MinEntropyBits is checked with go-password-validator's estimate.
+	MinEntropyBits float64
+	// MinCharacterClasses is how many of lowercase, uppercase, digits and
+	// symbols the password must mix.
+	MinCharacterClasses int
+	// MaxRepeat is the longest allowed run of one character.
+	MaxRepeat int
+	// DenyEmailParts rejects passwords that contain the user's email address
+	// or a word of it.
+	DenyEmailParts bool
+	// Breached rejects passwords found in a breach corpus when set.
+	Breached BreachChecker
+	// History is how many of the user's latest passwords, the current one
+	// included, a new password may not repeat. Check leaves this rule to the
+	// caller, which has the stored hashes.
+	History int
+}
+
+// DefaultPasswordPolicy keeps the historical 8 character minimum and 60 bits
+// of entropy. The maximum only bounds hashing cost.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:      8,
+	MaxLength:      64,
+	MinEntropyBits: 60,
+	DenyEmailParts: true,
+	History:        5,
+}
+
+type PasswordViolation struct {
+	Reason      string
+	Description string
+}
+
+// PasswordPolicyError lists every rule a password broke.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, v.Description)
+	}
+	return "password rejected: " + strings.Join(descriptions, "; ")
+}
+
+// ReusedError reports a password that matches one of the last History
+// passwords.
+func (p PasswordPolicy) ReusedError() error {
+	return &PasswordPolicyError{Violations: []PasswordViolation{{
+		Reason:      ReasonReused,
+		Description: fmt.Sprintf("password must differ from your last %d passwords", p.History),
+	}}}
+}
+
+// Check returns a *PasswordPolicyError if password breaks any rule. email is
+// the address of the account the password is for.
+func (p PasswordPolicy) Check(password, email string) error {
+	var violations []PasswordViolation
+	add := func(reason, format string, args ...any) {
+		violations = append(violations, PasswordViolation{Reason: reason, Description: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		add(ReasonTooShort, "password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add(ReasonTooLong, "password must be at most %d characters", p.MaxLength)
+	}
+
+	if p.MinEntropyBits > 0 && passwordValidator.GetEntropy(password) < p.MinEntropyBits {
+		add(ReasonLowEntropy, "password is too easy to guess, use a longer password or more kinds of characters")
+	}
 
-func ValidatePassword(password string) error {
-	if err := validate.Var(password, "required,min=8,max=32"); err != nil {
-		return status.Error(codes.InvalidArgument, "password length must be between 8 and 32 characters")
+	if p.MinCharacterClasses > 0 && characterClasses(password) < p.MinCharacterClasses {
+		add(ReasonCharacterClasses, "password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharacterClasses)
 	}
+
+	if p.MaxRepeat > 0 && longestRun(password) > p.MaxRepeat {
+		add(ReasonRepeated, "password must not repeat a character more than %d times in a row", p.MaxRepeat)
+	}
+
+	if p.DenyEmailParts && containsEmailPart(password, email) {
+		add(ReasonContainsEmail, "password must not contain your email address")
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		add(ReasonBreached, "password appears in a known data breach, choose a different one")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
 	return nil
 }
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+
+	return classes
+}
+
+func longestRun(password string) int {
+	longest, run := 0, 0
+	var prev rune
+
+	for i, r := range []rune(password) {
+		if i > 0 && r == prev {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		longest = max(longest, run)
+	}
+
+	return longest
+}
+
+// containsEmailPart checks the local part, its words and the first label of
+// the domain, so "jane.doe@acme.com" denies "jane", "doe" and "acme".
+func containsEmailPart(password, email string) bool {
+	local, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok {
+		return false
+	}
+
+	parts := strings.FieldsFunc(local, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	parts = append(parts, local)
+
+	if label, _, _ := strings.Cut(domain, "."); label != "" {
+		parts = append(parts, label)
+	}
+
+	password = strings.ToLower(password)
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= minEmailPart && strings.Contains(password, part) {
+			return true
+		}
+	}
+
+	return false
+}
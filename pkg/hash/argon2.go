@@ -0,0 +1,138 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params are the argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the second recommendation of RFC 9106.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var errInvalidArgon2Hash = errors.New("hash: invalid argon2id hash")
+
+// Limits on the parameters of stored hashes. argon2.IDKey panics on zero
+// iterations or parallelism, and an imported hash with a huge memory cost
+// would make every login allocate it.
+const (
+	argon2MaxMemory      = 256 * 1024 // KiB
+	argon2MaxIterations  = 16
+	argon2MaxParallelism = 16
+	argon2MinSaltLength  = 8
+	argon2MaxSaltLength  = 64
+	argon2MinKeyLength   = 4
+	argon2MaxKeyLength   = 64
+)
+
+var b64 = base64.RawStdEncoding
+
+type argon2Hasher struct {
+	params Argon2Params
+}
+
+func NewArgon2id(params Argon2Params) Hasher {
+	return &argon2Hasher{params: params}
+}
+
+func (h *argon2Hasher) IDs() []string {
+	return []string{"argon2id"}
+}
+
+// Hash returns $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+func (h *argon2Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (h *argon2Hasher) Verify(password, encoded string) (bool, error) {
+	p, salt, key, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (h *argon2Hasher) Valid(encoded string) bool {
+	_, _, _, err := decodeArgon2(encoded)
+	return err == nil
+}
+
+func (h *argon2Hasher) NeedsRehash(encoded string) bool {
+	p, salt, key, err := decodeArgon2(encoded)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory < h.params.Memory ||
+		p.Iterations < h.params.Iterations ||
+		p.Parallelism < h.params.Parallelism ||
+		uint32(len(salt)) < h.params.SaltLength ||
+		uint32(len(key)) < h.params.KeyLength
+}
+
+func decodeArgon2(encoded string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil ||
+		p.Memory < 1 || p.Memory > argon2MaxMemory ||
+		p.Iterations < 1 || p.Iterations > argon2MaxIterations ||
+		p.Parallelism < 1 || p.Parallelism > argon2MaxParallelism {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil || len(salt) < argon2MinSaltLength || len(salt) > argon2MaxSaltLength {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) < argon2MinKeyLength || len(key) > argon2MaxKeyLength {
+		return p, nil, nil, errInvalidArgon2Hash
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,37 @@
+package hash
+
+import "golang.org/x/crypto/bcrypt"
+
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+type bcryptHasher struct {
+	cost int
+}
+
+// NewBcrypt returns a bcrypt hasher. bcrypt ignores everything past the 72nd
+// byte of a password, so it is kept only to verify existing hashes.
+func NewBcrypt(cost int) Hasher {
+	return &bcryptHasher{cost: cost}
+}
+
+func (h *bcryptHasher) IDs() []string {
+	return []string{"2a", "2b", "2y"}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(bytes), err
+}
+
+func (h *bcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *bcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.cost
+}
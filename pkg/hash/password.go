@@ -1,13 +1,95 @@
 package hash
 
-import "golang.org/x/crypto/bcrypt"
+import "fmt"
 
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	h := defaultHasher()
+
+	ring := currentPepper()
+	if ring == nil {
+		return h.Hash(password)
+	}
+
+	inner, err := h.Hash(applyPepper(ring.keys[ring.current], password))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("$%s$v=%d%s", pepperID, ring.current, inner), nil
 }
 
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	if version, inner, ok := splitPepper(hash); ok {
+		ring := currentPepper()
+		if ring == nil {
+			return false
+		}
+
+		key, ok := ring.keys[version]
+		if !ok {
+			return false
+		}
+
+		password, hash = applyPepper(key, password), inner
+	}
+
+	scheme, ok := lookup(hash)
+	if !ok {
+		return false
+	}
+
+	valid, err := scheme.Verify(password, hash)
+	return err == nil && valid
+}
+
+// NeedsRehash reports whether hash should be replaced by a fresh one from
+// HashPassword, because it uses another scheme, outdated parameters or a
+// pepper version other than the current one.
+func NeedsRehash(hash string) bool {
+	ring := currentPepper()
+
+	version, inner, peppered := splitPepper(hash)
+	switch {
+	case peppered && (ring == nil || version != ring.current):
+		return true
+	case peppered:
+		hash = inner
+	case ring != nil:
+		return true
+	}
+
+	h := defaultHasher()
+
+	scheme, ok := lookup(hash)
+	if !ok || scheme != Scheme(h) {
+		return true
+	}
+
+	return h.NeedsRehash(hash)
+}
+
+// Supported reports whether hash belongs to a registered scheme and, if it
+// is peppered, whether its pepper version is configured.
+func Supported(hash string) bool {
+	if version, inner, ok := splitPepper(hash); ok {
+		ring := currentPepper()
+		if ring == nil {
+			return false
+		}
+		if _, ok := ring.keys[version]; !ok {
+			return false
+		}
+		hash = inner
+	}
+
+	scheme, ok := lookup(hash)
+	if !ok {
+		return false
+	}
+
+	if v, ok := scheme.(Validator); ok {
+		return v.Valid(hash)
+	}
+
+	return true
 }
@@ -0,0 +1,77 @@
+package hash
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errInvalidPBKDF2Hash = errors.New("hash: invalid pbkdf2-sha256 hash")
+
+// pbkdf2MaxIterations keeps an imported hash from tying up a CPU for seconds
+// per login. Current Django and OWASP settings stay well below it.
+const pbkdf2MaxIterations = 2_000_000
+
+type pbkdf2Scheme struct{}
+
+// NewPBKDF2SHA256 verifies hashes in the passlib format
+// $pbkdf2-sha256$<iterations>$<salt>$<key>, where salt and key use base64
+// with '.' in place of '+' and no padding.
+func NewPBKDF2SHA256() Scheme {
+	return pbkdf2Scheme{}
+}
+
+func (pbkdf2Scheme) IDs() []string {
+	return []string{"pbkdf2-sha256"}
+}
+
+func (pbkdf2Scheme) Verify(password, encoded string) (bool, error) {
+	iterations, salt, key, err := parsePBKDF2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(key))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (pbkdf2Scheme) Valid(encoded string) bool {
+	_, _, _, err := parsePBKDF2(encoded)
+	return err == nil
+}
+
+func parsePBKDF2(encoded string) (iterations int, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return 0, nil, nil, errInvalidPBKDF2Hash
+	}
+
+	iterations, err = strconv.Atoi(parts[2])
+	if err != nil || iterations < 1 || iterations > pbkdf2MaxIterations {
+		return 0, nil, nil, errInvalidPBKDF2Hash
+	}
+
+	salt, err = decodeAdaptedBase64(parts[3])
+	if err != nil {
+		return 0, nil, nil, errInvalidPBKDF2Hash
+	}
+
+	key, err = decodeAdaptedBase64(parts[4])
+	if err != nil || len(key) == 0 {
+		return 0, nil, nil, errInvalidPBKDF2Hash
+	}
+
+	return iterations, salt, key, nil
+}
+
+func decodeAdaptedBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.ReplaceAll(s, ".", "+"))
+}
@@ -0,0 +1,80 @@
+package hash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MinPepperLength is the minimum size of a pepper key in bytes.
+const MinPepperLength = 32
+
+// pepperID prefixes hashes of peppered passwords:
+// $pepper$v=<version>$<hash of the peppered password>.
+const pepperID = "pepper"
+
+type pepperRing struct {
+	keys    map[int][]byte
+	current int
+}
+
+var pepper *pepperRing
+
+// SetPepper enables an HMAC-SHA256 pepper applied to passwords before they
+// are hashed. New hashes use the key of version current and record that
+// version; the other keys verify older hashes until they are rehashed.
+// Removing a version makes every hash that still uses it unverifiable.
+func SetPepper(keys map[int][]byte, current int) error {
+	if _, ok := keys[current]; !ok {
+		return fmt.Errorf("hash: no pepper for version %d", current)
+	}
+
+	for version, key := range keys {
+		if len(key) < MinPepperLength {
+			return fmt.Errorf("hash: pepper version %d is shorter than %d bytes", version, MinPepperLength)
+		}
+	}
+
+	mu.Lock()
+	pepper = &pepperRing{keys: keys, current: current}
+	mu.Unlock()
+
+	return nil
+}
+
+func currentPepper() *pepperRing {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return pepper
+}
+
+func applyPepper(key []byte, password string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// splitPepper separates the pepper version from the inner hash. ok is false
+// for hashes stored without a pepper.
+func splitPepper(encoded string) (version int, inner string, ok bool) {
+	rest, found := strings.CutPrefix(encoded, "$"+pepperID+"$v=")
+	if !found {
+		return 0, "", false
+	}
+
+	v, inner, found := strings.Cut(rest, "$")
+	if !found {
+		return 0, "", false
+	}
+
+	version, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return version, "$" + inner, true
+}
@@ -0,0 +1,96 @@
+// Package hash stores passwords. Hashes are self-describing strings in the
+// PHC format ($<id>$...), or the modular crypt format for bcrypt, so that
+// several schemes can be verified side by side while new hashes use the
+// current one.
+package hash
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownScheme = errors.New("hash: unknown scheme")
+
+// Scheme verifies hashes of one format.
+type Scheme interface {
+	// IDs lists the identifiers between the first two '$' of its hashes.
+	IDs() []string
+	Verify(password, encoded string) (bool, error)
+}
+
+// Validator is implemented by schemes that can check a hash's format and
+// parameters without a password, so that Supported rejects hashes Verify
+// would refuse.
+type Validator interface {
+	Valid(encoded string) bool
+}
+
+// Hasher is a Scheme that can also produce new hashes.
+type Hasher interface {
+	Scheme
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether encoded was produced with weaker parameters
+	// than the hasher currently uses.
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	mu      sync.RWMutex
+	schemes = map[string]Scheme{}
+	current Hasher
+)
+
+func init() {
+	Register(NewBcrypt(DefaultBcryptCost))
+	Register(NewPBKDF2SHA256())
+	SetDefault(NewArgon2id(DefaultArgon2Params))
+}
+
+// Register makes a scheme available to CheckPasswordHash.
+func Register(scheme Scheme) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, id := range scheme.IDs() {
+		schemes[id] = scheme
+	}
+}
+
+// SetDefault registers h and uses it for new hashes.
+func SetDefault(h Hasher) {
+	Register(h)
+
+	mu.Lock()
+	current = h
+	mu.Unlock()
+}
+
+func lookup(encoded string) (Scheme, bool) {
+	id, ok := schemeID(encoded)
+	if !ok {
+		return nil, false
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	scheme, ok := schemes[id]
+	return scheme, ok
+}
+
+func schemeID(encoded string) (string, bool) {
+	if !strings.HasPrefix(encoded, "$") {
+		return "", false
+	}
+
+	id, _, ok := strings.Cut(encoded[1:], "$")
+	return id, ok && id != ""
+}
+
+func defaultHasher() Hasher {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current
+}
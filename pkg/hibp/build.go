@@ -0,0 +1,104 @@
+package hibp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Build writes an index to path from the HIBP "ordered by hash" SHA-1 text
+// file, whose lines look like "<40 hex digits>:<count>". Lines must be
+// sorted; repeated prefixes are stored once.
+func Build(r io.Reader, path string, recordSize int) (int, error) {
+	if recordSize < 1 || recordSize > sha1.Size {
+		return 0, fmt.Errorf("hibp: record size must be between 1 and %d", sha1.Size)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := build(r, f, recordSize)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func build(r io.Reader, f *os.File, recordSize int) (int, error) {
+	// The count is patched in once all records are written.
+	if _, err := f.Write(make([]byte, headerSize)); err != nil {
+		return 0, err
+	}
+
+	w := bufio.NewWriterSize(f, 1<<20)
+	scanner := bufio.NewScanner(r)
+
+	var (
+		count int
+		prev  []byte
+		sum   = make([]byte, sha1.Size)
+	)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		digest, _, _ := strings.Cut(text, ":")
+		if len(digest) != 2*sha1.Size {
+			return 0, fmt.Errorf("hibp: line %d: expected a SHA-1 hash", line)
+		}
+		if _, err := hex.Decode(sum, []byte(digest)); err != nil {
+			return 0, fmt.Errorf("hibp: line %d: %w", line, err)
+		}
+
+		record := sum[:recordSize]
+
+		switch cmp := bytes.Compare(record, prev); {
+		case prev != nil && cmp < 0:
+			return 0, fmt.Errorf("hibp: line %d: input is not sorted by hash", line)
+		case prev != nil && cmp == 0:
+			continue
+		}
+
+		if _, err := w.Write(record); err != nil {
+			return 0, err
+		}
+
+		prev = append(prev[:0], record...)
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, magic[:])
+	header[len(magic)] = byte(recordSize)
+	binary.BigEndian.PutUint64(header[len(magic)+1:], uint64(count))
+
+	if _, err := f.WriteAt(header, 0); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,121 @@
+// Package hibp checks passwords against a local copy of the Have I Been
+// Pwned password list, without network access.
+//
+// The index is a header followed by the sorted, truncated SHA-1 hashes of the
+// breached passwords. It is memory-mapped and searched with a binary search,
+// so lookups touch only a few pages and the index does not need to fit in
+// memory.
+package hibp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// magic starts every index file and versions its layout.
+var magic = [8]byte{'H', 'I', 'B', 'P', 'I', 'D', 'X', '1'}
+
+// headerSize is the magic, the record size and the record count.
+const headerSize = len(magic) + 1 + 8
+
+// DefaultRecordSize keeps the first 10 bytes of each SHA-1. At a billion
+// entries the chance that an unrelated password matches is about 1e-15.
+const DefaultRecordSize = 10
+
+var ErrInvalidIndex = errors.New("hibp: invalid index file")
+
+// Index is an opened index file. It is safe for concurrent use.
+type Index struct {
+	data       []byte
+	records    []byte
+	recordSize int
+	count      int
+	unmap      func() error
+}
+
+func Open(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() < int64(headerSize) {
+		return nil, ErrInvalidIndex
+	}
+
+	data, unmap, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("hibp: map %s: %w", path, err)
+	}
+
+	idx, err := parse(data)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	idx.unmap = unmap
+
+	return idx, nil
+}
+
+func parse(data []byte) (*Index, error) {
+	if !bytes.Equal(data[:len(magic)], magic[:]) {
+		return nil, ErrInvalidIndex
+	}
+
+	recordSize := int(data[len(magic)])
+	count := binary.BigEndian.Uint64(data[len(magic)+1 : headerSize])
+
+	if recordSize < 1 || recordSize > sha1.Size || uint64(len(data)-headerSize) != count*uint64(recordSize) {
+		return nil, ErrInvalidIndex
+	}
+
+	return &Index{
+		data:       data,
+		records:    data[headerSize:],
+		recordSize: recordSize,
+		count:      int(count),
+	}, nil
+}
+
+// Len returns the number of hashes in the index.
+func (idx *Index) Len() int {
+	return idx.count
+}
+
+// Contains reports whether password appears in the breach corpus.
+func (idx *Index) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	return idx.containsHash(sum[:idx.recordSize])
+}
+
+func (idx *Index) containsHash(prefix []byte) bool {
+	size := idx.recordSize
+
+	i := sort.Search(idx.count, func(i int) bool {
+		return bytes.Compare(idx.records[i*size:(i+1)*size], prefix) >= 0
+	})
+
+	return i < idx.count && bytes.Equal(idx.records[i*size:(i+1)*size], prefix)
+}
+
+func (idx *Index) Close() error {
+	if idx.unmap == nil {
+		return nil
+	}
+
+	err := idx.unmap()
+	idx.unmap, idx.data, idx.records, idx.count = nil, nil, nil, 0
+	return err
+}
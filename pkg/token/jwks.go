@@ -0,0 +1,80 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// JWKSMaxAge is how long clients may cache the published key set. A rotated
+// key has to be published at least this long before it starts signing.
+const JWKSMaxAge = 5 * time.Minute
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS returns the public half of every asymmetric verification key.
+// Symmetric keys are never published.
+func PublicJWKS(keys KeySet) JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	for _, signer := range keys.VerificationKeys() {
+		jwk, err := publicJWK(signer)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func publicJWK(signer Signer) (JWK, error) {
+	jwk := JWK{
+		Kid: signer.KeyID(),
+		Use: "sig",
+		Alg: signer.Method().Alg(),
+	}
+
+	switch k := signer.VerificationKey().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(k.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = k.Curve.Params().Name
+		jwk.X = encodeBase64URL(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64URL(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64URL(k)
+	default:
+		return JWK{}, fmt.Errorf("key type %T cannot be published", k)
+	}
+
+	return jwk, nil
+}
+
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -7,40 +7,125 @@ import (
 
 	"github.com/eduardovfaleiro/gatekeeper/internal/model"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-func GenerateToken(userID model.ID, secret string) (string, error) {
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type Claims struct {
+	UserID    model.ID
+	SessionID model.ID
+	// EmailVerified is false when the token was issued to a user who has not
+	// confirmed their email address yet.
+	EmailVerified bool
+	// TokenVersion is the user's token version when the token was issued.
+	TokenVersion int
+	TokenID      string
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+}
+
+func GenerateToken(userID, sessionID model.ID, emailVerified bool, tokenVersion int, keys KeySet) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub": userID.String(),
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-		"iat": time.Now().Unix(),
+		"sub":            userID.String(),
+		"sid":            sessionID.String(),
+		"email_verified": emailVerified,
+		"ver":            tokenVersion,
+		"jti":            uuid.New().String(),
+		"exp":            now.Add(AccessTokenTTL).Unix(),
+		"iat":            now.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	signer := keys.SigningKey()
+
+	token := jwt.NewWithClaims(signer.Method(), claims)
+	if kid := signer.KeyID(); kid != "" {
+		token.Header["kid"] = kid
+	}
+	return token.SignedString(signer.SigningKey())
 }
 
-func ValidateToken(tokenStr string, secret string) (model.ID, error) {
+func ValidateToken(tokenStr string, keys KeySet) (*Claims, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+
+		signer, ok := keys.VerificationKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+
+		if token.Method.Alg() != signer.Method().Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(secret), nil
+		return signer.VerificationKey(), nil
 	})
 
 	if err != nil || !token.Valid {
-		return model.ID{}, fmt.Errorf("invalid token: %w", err)
+		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return model.ID{}, errors.New("invalid claims")
+		return nil, errors.New("invalid claims")
 	}
 
 	userIDStr, ok := claims["sub"].(string)
 	if !ok {
-		return model.ID{}, errors.New("subject not found in token")
+		return nil, errors.New("subject not found in token")
+	}
+
+	userID, err := model.ParseID(userIDStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens issued before sessions existed carry no sid.
+	var sessionID model.ID
+	if sid, ok := claims["sid"].(string); ok {
+		if sessionID, err = model.ParseID(sid); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tokens issued before email verification existed carry no claim and
+	// belong to accounts that were verified by the migration.
+	emailVerified := true
+	if v, ok := claims["email_verified"].(bool); ok {
+		emailVerified = v
+	}
+
+	// Tokens issued before token versions existed are at version zero.
+	var tokenVersion int
+	if v, ok := claims["ver"].(float64); ok {
+		tokenVersion = int(v)
+	}
+
+	tokenID, ok := claims["jti"].(string)
+	if !ok {
+		return nil, errors.New("token id not found in token")
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return nil, errors.New("issued at not found in token")
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return nil, errors.New("expiration not found in token")
 	}
 
-	return model.ParseID(userIDStr)
+	return &Claims{
+		UserID:        userID,
+		SessionID:     sessionID,
+		EmailVerified: emailVerified,
+		TokenVersion:  tokenVersion,
+		TokenID:       tokenID,
+		IssuedAt:      issuedAt.Time,
+		ExpiresAt:     expiresAt.Time,
+	}, nil
 }
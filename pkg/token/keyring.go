@@ -0,0 +1,71 @@
+package token
+
+import "sync"
+
+// KeyRing is a KeySet whose contents can be swapped at runtime. New tokens are
+// signed with the active key; tokens signed by a retiring key stay valid until
+// that key is retired and dropped from the ring. Pending keys are published
+// and accepted before they sign anything, so that every instance and JWKS
+// client knows a key by the time it becomes active.
+type KeyRing struct {
+	mu       sync.RWMutex
+	active   Signer
+	pending  []Signer
+	retiring []Signer
+}
+
+func NewKeyRing() *KeyRing {
+	return &KeyRing{}
+}
+
+func (r *KeyRing) Set(active Signer, pending, retiring []Signer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.active = active
+	r.pending = pending
+	r.retiring = retiring
+}
+
+func (r *KeyRing) SigningKey() Signer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.active
+}
+
+func (r *KeyRing) VerificationKey(kid string) (Signer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.active != nil && r.active.KeyID() == kid {
+		return r.active, true
+	}
+
+	for _, s := range r.pending {
+		if s.KeyID() == kid {
+			return s, true
+		}
+	}
+
+	for _, s := range r.retiring {
+		if s.KeyID() == kid {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *KeyRing) VerificationKeys() []Signer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]Signer, 0, len(r.pending)+len(r.retiring)+1)
+	if r.active != nil {
+		keys = append(keys, r.active)
+	}
+	keys = append(keys, r.pending...)
+
+	return append(keys, r.retiring...)
+}
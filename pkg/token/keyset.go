@@ -0,0 +1,37 @@
+package token
+
+// KeySet decides which key signs new tokens and which keys are trusted when
+// verifying them.
+type KeySet interface {
+	SigningKey() Signer
+	// VerificationKey returns the key with the given id if tokens signed by it
+	// are still accepted.
+	VerificationKey(kid string) (Signer, bool)
+	VerificationKeys() []Signer
+}
+
+type staticKeySet struct {
+	signer Signer
+}
+
+// NewStaticKeySet returns a key set backed by a single key. Tokens without a
+// kid header are verified against it, which keeps tokens issued before key
+// ids were introduced valid.
+func NewStaticKeySet(signer Signer) KeySet {
+	return &staticKeySet{signer: signer}
+}
+
+func (k *staticKeySet) SigningKey() Signer {
+	return k.signer
+}
+
+func (k *staticKeySet) VerificationKey(kid string) (Signer, bool) {
+	if kid == "" || kid == k.signer.KeyID() {
+		return k.signer, true
+	}
+	return nil, false
+}
+
+func (k *staticKeySet) VerificationKeys() []Signer {
+	return []Signer{k.signer}
+}
@@ -0,0 +1,195 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Signer holds one key that can sign and verify JWTs.
+type Signer interface {
+	KeyID() string
+	Method() jwt.SigningMethod
+	SigningKey() any
+	VerificationKey() any
+}
+
+type hmacSigner struct {
+	kid    string
+	secret []byte
+}
+
+func NewHMACSigner(kid string, secret []byte) Signer {
+	return &hmacSigner{kid: kid, secret: secret}
+}
+
+func (s *hmacSigner) KeyID() string             { return s.kid }
+func (s *hmacSigner) Method() jwt.SigningMethod { return jwt.SigningMethodHS256 }
+func (s *hmacSigner) SigningKey() any           { return s.secret }
+func (s *hmacSigner) VerificationKey() any      { return s.secret }
+
+type asymmetricSigner struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+}
+
+func (s *asymmetricSigner) KeyID() string             { return s.kid }
+func (s *asymmetricSigner) Method() jwt.SigningMethod { return s.method }
+func (s *asymmetricSigner) SigningKey() any           { return s.privateKey }
+func (s *asymmetricSigner) VerificationKey() any      { return s.privateKey.Public() }
+
+// NewAsymmetricSigner wraps an RSA, P-256 ECDSA or Ed25519 private key. The key
+// id is the RFC 7638 thumbprint of the public key.
+func NewAsymmetricSigner(privateKey crypto.Signer) (Signer, error) {
+	var method jwt.SigningMethod
+
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("rsa key must be at least 2048 bits")
+		}
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.New("ecdsa key must use the P-256 curve")
+		}
+		method = jwt.SigningMethodES256
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+
+	s := &asymmetricSigner{method: method, privateKey: privateKey}
+
+	jwk, err := publicJWK(s)
+	if err != nil {
+		return nil, err
+	}
+	s.kid = jwk.thumbprint()
+
+	return s, nil
+}
+
+// GenerateSigner creates a signer with a fresh private key for the given
+// asymmetric algorithm.
+func GenerateSigner(alg string) (Signer, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+
+	switch alg {
+	case AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("token.GenerateSigner: %w", err)
+	}
+
+	return NewAsymmetricSigner(key)
+}
+
+// ParseSignerPEM reads a PKCS#8, PKCS#1 or SEC 1 encoded private key.
+func ParseSignerPEM(data []byte) (Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var (
+		key any
+		err error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("token.ParseSignerPEM: %w", err)
+	}
+
+	privateKey, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	return NewAsymmetricSigner(privateKey)
+}
+
+// MarshalSignerPEM encodes the private key of an asymmetric signer as PKCS#8.
+func MarshalSignerPEM(signer Signer) ([]byte, error) {
+	if _, ok := signer.(*asymmetricSigner); !ok {
+		return nil, errors.New("only asymmetric keys can be exported")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer.SigningKey())
+	if err != nil {
+		return nil, fmt.Errorf("token.MarshalSignerPEM: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k JWK) thumbprint() string {
+	// RFC 7638 requires the required members only, in lexicographic order.
+	var members any
+	switch k.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{k.Crv, k.Kty, k.X}
+	}
+
+	b, _ := json.Marshal(members)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}